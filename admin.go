@@ -0,0 +1,266 @@
+package log
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+)
+
+// AdminHandler returns an http.Handler for runtime control of l, a logger built
+// by New or NewFromConfig. Mount it under a prefix with http.StripPrefix and
+// behind your own authentication; it has none. It serves JSON:
+//
+//	GET    /                   {"level": ..., "components": {...}, "filters": [...]}
+//	GET    /level              {"level": "INFO"}
+//	PUT    /level              {"level": "trace"}
+//	GET    /components         {"db": "WARN"}
+//	PUT    /components         {"db": "warn", "db.pool": "trace"}  (replaces all)
+//	PUT    /components/{name}  {"level": "debug"}
+//	DELETE /components/{name}
+//	GET    /filters            [{"action": "deny", ...}, ...]
+//	PUT    /filters            [...]                              (replaces all)
+//	POST   /filters            {"action": "deny", ...}            (appends one)
+//
+// Levels and filters use the Config formats. Filter endpoints need the
+// FilterHandler that WithFilters or NewFromConfig installs.
+func AdminHandler(l Logger) http.Handler {
+	return &adminHandler{logger: l}
+}
+
+// adminHandler serves AdminHandler.
+type adminHandler struct {
+	logger Logger
+}
+
+// adminState is the body of GET /.
+type adminState struct {
+	Level      string            `json:"level"`
+	Components map[string]string `json:"components"`
+	Filters    []FilterConfig    `json:"filters,omitempty"`
+}
+
+// levelBody is the body of the level endpoints.
+type levelBody struct {
+	Level string `json:"level"`
+}
+
+// ServeHTTP routes the request to the resource named by its path.
+func (h *adminHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	path := strings.Trim(r.URL.Path, "/")
+	switch {
+	case path == "":
+		h.serveState(w, r)
+	case path == "level":
+		h.serveLevel(w, r)
+	case path == "components":
+		h.serveComponents(w, r)
+	case strings.HasPrefix(path, "components/"):
+		h.serveComponent(w, r, strings.TrimPrefix(path, "components/"))
+	case path == "filters":
+		h.serveFilters(w, r)
+	default:
+		writeError(w, http.StatusNotFound, fmt.Errorf("no such resource %q", r.URL.Path))
+	}
+}
+
+func (h *adminHandler) serveState(w http.ResponseWriter, r *http.Request) {
+	if !allowMethods(w, r, http.MethodGet) {
+		return
+	}
+	state := adminState{
+		Level:      levelString(h.logger.Level()),
+		Components: componentStrings(h.logger.Components()),
+	}
+	if fh := filterHandlerOf(h.logger); fh != nil {
+		filters, err := filterConfigs(fh.Filters())
+		if err != nil {
+			writeError(w, http.StatusConflict, err)
+			return
+		}
+		state.Filters = filters
+	}
+	writeJSON(w, http.StatusOK, state)
+}
+
+func (h *adminHandler) serveLevel(w http.ResponseWriter, r *http.Request) {
+	if !allowMethods(w, r, http.MethodGet, http.MethodPut) {
+		return
+	}
+	if r.Method == http.MethodPut {
+		if !controlsLevel(h.logger) {
+			writeError(w, http.StatusConflict, errors.New("the logger's level is owned by its handler"))
+			return
+		}
+		var body levelBody
+		if !readJSON(w, r, &body) {
+			return
+		}
+		level, err := ParseLevel(body.Level)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, &ConfigError{Field: "level", Err: err})
+			return
+		}
+		h.logger.SetLevel(level)
+	}
+	writeJSON(w, http.StatusOK, levelBody{Level: levelString(h.logger.Level())})
+}
+
+func (h *adminHandler) serveComponents(w http.ResponseWriter, r *http.Request) {
+	if !allowMethods(w, r, http.MethodGet, http.MethodPut) {
+		return
+	}
+	components := h.logger.Components()
+	if r.Method == http.MethodPut {
+		var body map[string]string
+		if !readJSON(w, r, &body) {
+			return
+		}
+		levels := make(map[string]slog.Level, len(body))
+		for name, value := range body {
+			level, err := ParseLevel(value)
+			if err != nil {
+				writeError(w, http.StatusBadRequest, &ConfigError{Field: fmt.Sprintf("components[%q]", name), Err: err})
+				return
+			}
+			levels[name] = level
+		}
+		components.Replace(levels)
+	}
+	writeJSON(w, http.StatusOK, componentStrings(components))
+}
+
+func (h *adminHandler) serveComponent(w http.ResponseWriter, r *http.Request, name string) {
+	if !allowMethods(w, r, http.MethodPut, http.MethodDelete) {
+		return
+	}
+	components := h.logger.Components()
+	if r.Method == http.MethodDelete {
+		components.Unset(name)
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	var body levelBody
+	if !readJSON(w, r, &body) {
+		return
+	}
+	level, err := ParseLevel(body.Level)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, &ConfigError{Field: "level", Err: err})
+		return
+	}
+	components.Set(name, level)
+	writeJSON(w, http.StatusOK, levelBody{Level: levelString(level)})
+}
+
+func (h *adminHandler) serveFilters(w http.ResponseWriter, r *http.Request) {
+	if !allowMethods(w, r, http.MethodGet, http.MethodPut, http.MethodPost) {
+		return
+	}
+	fh := filterHandlerOf(h.logger)
+	if fh == nil {
+		writeError(w, http.StatusConflict, errors.New("the logger has no FilterHandler; build it with WithFilters or NewFromConfig"))
+		return
+	}
+
+	switch r.Method {
+	case http.MethodPut:
+		var body []FilterConfig
+		if !readJSON(w, r, &body) {
+			return
+		}
+		filters, err := Config{Filters: body}.filters()
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+		fh.SetFilters(filters)
+	case http.MethodPost:
+		var body FilterConfig
+		if !readJSON(w, r, &body) {
+			return
+		}
+		filter, err := body.filter()
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+		fh.AddFilter(filter)
+	}
+	filters, err := filterConfigs(fh.Filters())
+	if err != nil {
+		writeError(w, http.StatusConflict, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, filters)
+}
+
+// filterHandlerOf returns the FilterHandler New installed for l, or nil.
+func filterHandlerOf(l Logger) *FilterHandler {
+	if lg, ok := l.(*logger); ok {
+		return lg.filters
+	}
+	return nil
+}
+
+// componentStrings renders the overrides with level names.
+func componentStrings(c *Components) map[string]string {
+	levels := c.Levels()
+	out := make(map[string]string, len(levels))
+	for name, level := range levels {
+		out[name] = levelString(level)
+	}
+	return out
+}
+
+// filterConfigs describes filters in the Config format, refusing filters the
+// format cannot describe faithfully.
+func filterConfigs(filters []Filter) ([]FilterConfig, error) {
+	out := make([]FilterConfig, len(filters))
+	for i, f := range filters {
+		if err := f.describable(); err != nil {
+			return nil, fmt.Errorf("filters[%d]: %w", i, err)
+		}
+		out[i] = f.config()
+	}
+	return out, nil
+}
+
+// allowMethods reports whether r uses one of methods, answering 405 with an
+// Allow header when it does not.
+func allowMethods(w http.ResponseWriter, r *http.Request, methods ...string) bool {
+	for _, m := range methods {
+		if r.Method == m {
+			return true
+		}
+	}
+	w.Header().Set("Allow", strings.Join(methods, ", "))
+	writeError(w, http.StatusMethodNotAllowed, fmt.Errorf("method %s not allowed", r.Method))
+	return false
+}
+
+// readJSON decodes the request body into v, rejecting unknown fields, and
+// answers 400 when it cannot.
+func readJSON(w http.ResponseWriter, r *http.Request, v any) bool {
+	dec := json.NewDecoder(r.Body)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(v); err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("decode body: %w", err))
+		return false
+	}
+	return true
+}
+
+// writeJSON writes v as a JSON response with status.
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+// writeError writes err as a JSON {"error": ...} response with status.
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, map[string]string{"error": err.Error()})
+}
@@ -0,0 +1,171 @@
+package log
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// do sends a request to h and returns the response status and body.
+func do(t *testing.T, h http.Handler, method, path, body string) (int, string) {
+	t.Helper()
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	return rec.Code, rec.Body.String()
+}
+
+func Test_AdminHandler_Level(t *testing.T) {
+	var buf lockedBuffer
+	logger := New(WithText(&buf), WithLevel(slog.LevelInfo))
+	h := AdminHandler(logger)
+
+	code, body := do(t, h, http.MethodGet, "/level", "")
+	if code != http.StatusOK || !strings.Contains(body, `"level":"INFO"`) {
+		t.Fatalf("GET /level = %d %s, want 200 with INFO", code, body)
+	}
+
+	code, body = do(t, h, http.MethodPut, "/level", `{"level": "trace"}`)
+	if code != http.StatusOK || !strings.Contains(body, `"level":"TRACE"`) {
+		t.Fatalf("PUT /level = %d %s, want 200 with TRACE", code, body)
+	}
+	logger.Trace("now visible")
+	if !strings.Contains(buf.String(), "now visible") {
+		t.Fatalf("PUT /level did not lower the logger's level: %q", buf.String())
+	}
+
+	code, body = do(t, h, http.MethodPut, "/level", `{"level": "loud"}`)
+	if code != http.StatusBadRequest || !strings.Contains(body, "level") {
+		t.Fatalf("PUT /level with a bad level = %d %s, want 400 naming the field", code, body)
+	}
+	if got := logger.Level(); got != LevelTrace {
+		t.Fatalf("Level() = %v after a rejected PUT, want %v", got, LevelTrace)
+	}
+}
+
+func Test_AdminHandler_Components(t *testing.T) {
+	var buf lockedBuffer
+	logger := New(WithText(&buf), WithLevel(slog.LevelInfo))
+	h := AdminHandler(logger)
+	pool := logger.Named("db.pool")
+
+	code, body := do(t, h, http.MethodPut, "/components", `{"db": "warn", "db.pool": "debug"}`)
+	if code != http.StatusOK {
+		t.Fatalf("PUT /components = %d %s, want 200", code, body)
+	}
+	pool.Debug("pool-debug")
+
+	code, _ = do(t, h, http.MethodPut, "/components/http", `{"level": "error"}`)
+	if code != http.StatusOK {
+		t.Fatalf("PUT /components/http = %d, want 200", code)
+	}
+	code, _ = do(t, h, http.MethodDelete, "/components/db.pool", "")
+	if code != http.StatusNoContent {
+		t.Fatalf("DELETE /components/db.pool = %d, want 204", code)
+	}
+	pool.Info("pool-info") // falls back to db=warn
+
+	code, body = do(t, h, http.MethodGet, "/components", "")
+	var got map[string]string
+	if err := json.Unmarshal([]byte(body), &got); err != nil || code != http.StatusOK {
+		t.Fatalf("GET /components = %d %s (%v)", code, body, err)
+	}
+	if len(got) != 2 || got["db"] != "WARN" || got["http"] != "ERROR" {
+		t.Fatalf("GET /components = %v, want db=WARN http=ERROR", got)
+	}
+
+	out := buf.String()
+	if !strings.Contains(out, "pool-debug") || strings.Contains(out, "pool-info") {
+		t.Fatalf("component overrides did not take effect: %q", out)
+	}
+}
+
+func Test_AdminHandler_Filters(t *testing.T) {
+	var buf lockedBuffer
+	logger := New(WithText(&buf), WithFilters(Deny().Message("a")))
+	h := AdminHandler(logger)
+
+	code, body := do(t, h, http.MethodGet, "/filters", "")
+	if code != http.StatusOK || !strings.Contains(body, `"message":"a"`) {
+		t.Fatalf("GET /filters = %d %s, want the configured filter", code, body)
+	}
+
+	code, body = do(t, h, http.MethodPut, "/filters", `[{"action": "deny", "message": "b"}]`)
+	if code != http.StatusOK {
+		t.Fatalf("PUT /filters = %d %s, want 200", code, body)
+	}
+	code, body = do(t, h, http.MethodPost, "/filters", `{"action": "shorten", "keys": ["body"], "limit": 5}`)
+	if code != http.StatusOK {
+		t.Fatalf("POST /filters = %d %s, want 200", code, body)
+	}
+
+	logger.Info("a")
+	logger.Info("b")
+	logger.Info("c", "body", "0123456789")
+
+	out := buf.String()
+	if !strings.Contains(out, "msg=a") || strings.Contains(out, "msg=b") || !strings.Contains(out, "body=01...") {
+		t.Fatalf("filters from PUT/POST were not applied: %q", out)
+	}
+
+	code, body = do(t, h, http.MethodPut, "/filters", `[{"action": "discard"}]`)
+	if code != http.StatusBadRequest || !strings.Contains(body, "filters[0].action") {
+		t.Fatalf("PUT /filters with a bad action = %d %s, want 400 naming the field", code, body)
+	}
+	code, body = do(t, h, http.MethodPut, "/filters", `[{"action": "hash", "keys": ["ip"]}]`)
+	if code != http.StatusBadRequest || !strings.Contains(body, "filters[0].key_env") {
+		t.Fatalf("PUT /filters with an unkeyed hash = %d %s, want 400 naming key_env", code, body)
+	}
+}
+
+func Test_AdminHandler_State(t *testing.T) {
+	logger := New(WithText(&lockedBuffer{}), WithLevel(slog.LevelWarn), WithFilters(Deny().Below(slog.LevelInfo)))
+	logger.Components().Set("db", LevelTrace)
+
+	code, body := do(t, AdminHandler(logger), http.MethodGet, "/", "")
+	var state struct {
+		Level      string            `json:"level"`
+		Components map[string]string `json:"components"`
+		Filters    []FilterConfig    `json:"filters"`
+	}
+	if err := json.Unmarshal([]byte(body), &state); err != nil || code != http.StatusOK {
+		t.Fatalf("GET / = %d %s (%v)", code, body, err)
+	}
+	if state.Level != "WARN" || state.Components["db"] != "TRACE" {
+		t.Fatalf("state = %+v, want level WARN and db=TRACE", state)
+	}
+	if len(state.Filters) != 1 || state.Filters[0].Action != "deny" || state.Filters[0].Below != "INFO" {
+		t.Fatalf("filters = %+v, want one deny below INFO", state.Filters)
+	}
+}
+
+func Test_AdminHandler_Errors(t *testing.T) {
+	tests := []struct {
+		name     string
+		logger   Logger
+		method   string
+		path     string
+		wantCode int
+	}{
+		{"unknown path", New(WithText(&lockedBuffer{})), http.MethodGet, "/nope", http.StatusNotFound},
+		{"wrong method", New(WithText(&lockedBuffer{})), http.MethodDelete, "/level", http.StatusMethodNotAllowed},
+		{"no filter handler", New(WithText(&lockedBuffer{})), http.MethodGet, "/filters", http.StatusConflict},
+		{"wrapped logger level", Wrap(slog.New(noopHandler{})), http.MethodPut, "/level", http.StatusConflict},
+		{"hash without key env", New(WithText(&lockedBuffer{}), WithFilters(Hash([]byte("k"), "ip"))), http.MethodGet, "/filters", http.StatusConflict},
+		{"state with hash without key env", New(WithText(&lockedBuffer{}), WithFilters(Hash([]byte("k"), "ip"))), http.MethodGet, "/", http.StatusConflict},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, body := do(t, AdminHandler(tt.logger), tt.method, tt.path, `{"level": "info"}`)
+			if code != tt.wantCode {
+				t.Fatalf("%s %s = %d %s, want %d", tt.method, tt.path, code, body, tt.wantCode)
+			}
+			if !strings.Contains(body, `"error"`) {
+				t.Fatalf("error response %q is not a JSON error", body)
+			}
+		})
+	}
+}
@@ -0,0 +1,361 @@
+package log
+
+import (
+	"context"
+	"log/slog"
+	"reflect"
+	"sync"
+)
+
+// OverflowPolicy is what an AsyncHandler does with a record when its queue is
+// full.
+type OverflowPolicy int
+
+const (
+	// OverflowBlock makes the logging call wait for room (the zero value).
+	OverflowBlock OverflowPolicy = iota
+	// OverflowDropNewest drops the record being logged.
+	OverflowDropNewest
+	// OverflowDropOldest drops the longest-queued record to make room.
+	OverflowDropOldest
+	// OverflowDropBelowLevel drops the record if it is below
+	// AsyncOptions.DropBelow, and waits for room otherwise.
+	OverflowDropBelowLevel
+)
+
+// AsyncOptions configures an AsyncHandler.
+type AsyncOptions struct {
+	// QueueSize is how many records may wait to be written (default 1024).
+	QueueSize int
+	// Overflow is what happens to a record logged while the queue is full.
+	Overflow OverflowPolicy
+	// DropBelow is the level under which OverflowDropBelowLevel drops records
+	// (default slog.LevelError).
+	DropBelow slog.Leveler
+}
+
+// AsyncStats are an AsyncHandler's counters.
+type AsyncStats struct {
+	// Queued is how many records are waiting to be written.
+	Queued int
+	// Dropped is how many records were dropped: by the overflow policy, by a
+	// blocked logging call whose context ended, or for arriving after Close.
+	Dropped uint64
+	// Failed is how many records the wrapped handler returned an error for.
+	Failed uint64
+}
+
+// AsyncHandler is a slog.Handler that queues records and writes them to the
+// wrapped handler on a background goroutine, so a stalled disk or a slow
+// exporter does not hold up the logging call. When the queue is full the
+// AsyncOptions.Overflow policy applies.
+//
+// Records are written in order. Call Flush to wait until the queue is empty,
+// and Close before exiting to write what is left and stop the goroutine:
+//
+//	async := log.NewAsyncHandler(slog.NewJSONHandler(file, nil), log.AsyncOptions{})
+//	logger := log.New(log.WithOutput(async))
+//	defer async.Close(context.Background())
+//
+// Attribute values are captured when the record is logged: LogValuers are
+// resolved on the logging goroutine, and byte slices, other slices and maps are
+// copied, so a caller may reuse them once the call returns. Values behind
+// pointers, and the elements of copied slices and maps, are still read later,
+// on the background goroutine.
+//
+// Errors from the wrapped handler have no caller to go to; they are counted in
+// Stats.
+type AsyncHandler struct {
+	handler slog.Handler
+	state   *asyncState
+}
+
+// asyncItem is a queued record with the handler, derived with any WithAttrs
+// and WithGroup, that writes it.
+type asyncItem struct {
+	handler slog.Handler
+	//nolint:containedctx // the logging call's values, detached from its cancellation, travel with the record.
+	ctx    context.Context
+	record slog.Record
+}
+
+// asyncState is the queue shared by derived AsyncHandlers.
+type asyncState struct {
+	overflow  OverflowPolicy
+	dropBelow slog.Leveler
+
+	mu sync.Mutex
+	// queue is a ring buffer of n items starting at head.
+	queue   []asyncItem
+	head, n int
+	// busy is set while the worker writes a record it has taken off the queue.
+	busy   bool
+	closed bool
+	// changed is closed and replaced whenever the queue or flags change, to
+	// wake everyone waiting on it.
+	changed chan struct{}
+	// done is closed when the worker has exited.
+	done    chan struct{}
+	dropped uint64
+	failed  uint64
+}
+
+var _ slog.Handler = (*AsyncHandler)(nil)
+
+// NewAsyncHandler wraps handler, starting the goroutine that writes to it.
+func NewAsyncHandler(handler slog.Handler, opts AsyncOptions) *AsyncHandler {
+	size := opts.QueueSize
+	if size <= 0 {
+		size = 1024
+	}
+	s := &asyncState{
+		overflow:  opts.Overflow,
+		dropBelow: opts.DropBelow,
+		queue:     make([]asyncItem, size),
+		changed:   make(chan struct{}),
+		done:      make(chan struct{}),
+	}
+	if s.dropBelow == nil {
+		s.dropBelow = slog.LevelError
+	}
+	go s.run()
+	return &AsyncHandler{handler: handler, state: s}
+}
+
+// Enabled reports whether the wrapped handler is enabled at level.
+func (h *AsyncHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.handler.Enabled(ctx, level)
+}
+
+// Handle queues the record. With a full queue it applies the overflow policy;
+// a call that waits for room gives up, dropping the record, when ctx ends.
+func (h *AsyncHandler) Handle(ctx context.Context, record slog.Record) error {
+	item := asyncItem{handler: h.handler, ctx: context.WithoutCancel(ctx), record: snapshotRecord(record)}
+
+	s := h.state
+	s.mu.Lock()
+	for {
+		if s.closed {
+			s.dropped++
+			s.mu.Unlock()
+			return nil
+		}
+		if s.n < len(s.queue) {
+			s.push(item)
+			s.mu.Unlock()
+			return nil
+		}
+
+		switch s.overflow {
+		case OverflowDropNewest:
+			s.dropped++
+			s.mu.Unlock()
+			return nil
+		case OverflowDropOldest:
+			s.pop()
+			s.dropped++
+			s.push(item)
+			s.mu.Unlock()
+			return nil
+		case OverflowDropBelowLevel:
+			if record.Level < s.dropBelow.Level() {
+				s.dropped++
+				s.mu.Unlock()
+				return nil
+			}
+		case OverflowBlock:
+			// wait for room below.
+		}
+
+		changed := s.changed
+		s.mu.Unlock()
+		select {
+		case <-changed:
+			s.mu.Lock()
+		case <-ctx.Done():
+			s.mu.Lock()
+			s.dropped++
+			s.mu.Unlock()
+			return ctx.Err()
+		}
+	}
+}
+
+// Flush waits until every queued record has been written, or ctx ends.
+func (h *AsyncHandler) Flush(ctx context.Context) error {
+	s := h.state
+	s.mu.Lock()
+	for s.n > 0 || s.busy {
+		changed := s.changed
+		s.mu.Unlock()
+		select {
+		case <-changed:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+		s.mu.Lock()
+	}
+	s.mu.Unlock()
+	return nil
+}
+
+// Close stops accepting records and waits until the queued ones have been
+// written and the goroutine has exited, or ctx ends. Records logged after
+// Close are dropped. Close is shared by every derived handler and may be
+// called more than once.
+func (h *AsyncHandler) Close(ctx context.Context) error {
+	s := h.state
+	s.mu.Lock()
+	if !s.closed {
+		s.closed = true
+		s.broadcast()
+	}
+	s.mu.Unlock()
+	select {
+	case <-s.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Stats returns the handler's counters.
+func (h *AsyncHandler) Stats() AsyncStats {
+	s := h.state
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return AsyncStats{Queued: s.n, Dropped: s.dropped, Failed: s.failed}
+}
+
+// run writes queued records until the handler is closed and the queue empty.
+func (s *asyncState) run() {
+	defer close(s.done)
+	s.mu.Lock()
+	for {
+		if s.n == 0 {
+			if s.closed {
+				s.mu.Unlock()
+				return
+			}
+			changed := s.changed
+			s.mu.Unlock()
+			<-changed
+			s.mu.Lock()
+			continue
+		}
+
+		item := s.pop()
+		s.busy = true
+		s.mu.Unlock()
+		err := item.handler.Handle(item.ctx, item.record)
+		s.mu.Lock()
+		if err != nil {
+			s.failed++
+		}
+		s.busy = false
+		s.broadcast()
+	}
+}
+
+// push appends item to the queue, which has room. The caller holds s.mu.
+func (s *asyncState) push(item asyncItem) {
+	s.queue[(s.head+s.n)%len(s.queue)] = item
+	s.n++
+	s.broadcast()
+}
+
+// pop removes and returns the oldest item of the non-empty queue. The caller
+// holds s.mu.
+func (s *asyncState) pop() asyncItem {
+	item := s.queue[s.head]
+	// release the record for the garbage collector.
+	s.queue[s.head] = asyncItem{}
+	s.head = (s.head + 1) % len(s.queue)
+	s.n--
+	return item
+}
+
+// broadcast wakes everyone waiting for a change. The caller holds s.mu.
+func (s *asyncState) broadcast() {
+	close(s.changed)
+	s.changed = make(chan struct{})
+}
+
+// snapshotRecord returns a copy of record whose attrs no longer depend on the
+// caller: see AsyncHandler.
+func snapshotRecord(record slog.Record) slog.Record {
+	out := slog.NewRecord(record.Time, record.Level, record.Message, record.PC)
+	attrs := make([]slog.Attr, 0, record.NumAttrs())
+	record.Attrs(func(attr slog.Attr) bool {
+		attrs = append(attrs, snapshotAttr(attr))
+		return true
+	})
+	out.AddAttrs(attrs...)
+	return out
+}
+
+// snapshotAttrs returns attrs with each one snapshotted.
+func snapshotAttrs(attrs []slog.Attr) []slog.Attr {
+	out := make([]slog.Attr, len(attrs))
+	for i, attr := range attrs {
+		out[i] = snapshotAttr(attr)
+	}
+	return out
+}
+
+// snapshotAttr resolves attr's value, descending into groups, and copies the
+// byte slices, slices and maps the caller might go on to change.
+func snapshotAttr(attr slog.Attr) slog.Attr {
+	attr.Value = attr.Value.Resolve()
+	switch attr.Value.Kind() {
+	case slog.KindGroup:
+		attr.Value = slog.GroupValue(snapshotAttrs(attr.Value.Group())...)
+	case slog.KindAny:
+		attr.Value = slog.AnyValue(snapshotAny(attr.Value.Any()))
+	default:
+		// the other kinds are held by value.
+	}
+	return attr
+}
+
+// snapshotAny returns a shallow copy of v if it is a slice or a map, and v
+// otherwise.
+func snapshotAny(v any) any {
+	if b, ok := v.([]byte); ok {
+		return append([]byte(nil), b...)
+	}
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Slice:
+		if rv.IsNil() {
+			return v
+		}
+		cp := reflect.MakeSlice(rv.Type(), rv.Len(), rv.Len())
+		reflect.Copy(cp, rv)
+		return cp.Interface()
+	case reflect.Map:
+		if rv.IsNil() {
+			return v
+		}
+		cp := reflect.MakeMapWithSize(rv.Type(), rv.Len())
+		iter := rv.MapRange()
+		for iter.Next() {
+			cp.SetMapIndex(iter.Key(), iter.Value())
+		}
+		return cp.Interface()
+	default:
+		return v
+	}
+}
+
+// WithAttrs returns an AsyncHandler sharing the same queue around the wrapped
+// handler's WithAttrs, snapshotting attrs as Handle does.
+func (h *AsyncHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &AsyncHandler{handler: h.handler.WithAttrs(snapshotAttrs(attrs)), state: h.state}
+}
+
+// WithGroup returns an AsyncHandler sharing the same queue around the wrapped
+// handler's WithGroup.
+func (h *AsyncHandler) WithGroup(name string) slog.Handler {
+	return &AsyncHandler{handler: h.handler.WithGroup(name), state: h.state}
+}
@@ -0,0 +1,206 @@
+package log
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+)
+
+// gateHandler holds every record until release is closed, then passes it to
+// recHandler.
+type gateHandler struct {
+	*recHandler
+	release chan struct{}
+}
+
+func (g gateHandler) Handle(ctx context.Context, r slog.Record) error {
+	<-g.release
+	return g.recHandler.Handle(ctx, r)
+}
+
+func messagesOf(records []slog.Record) []string {
+	out := make([]string, len(records))
+	for i, r := range records {
+		out[i] = r.Message
+	}
+	return out
+}
+
+func Test_AsyncHandler_Flush(t *testing.T) {
+	down := newRecHandler(LevelTrace)
+	h := NewAsyncHandler(down, AsyncOptions{})
+	t.Cleanup(func() { _ = h.Close(context.Background()) })
+
+	logger := slog.New(h).With("svc", "api")
+	for _, msg := range []string{"a", "b", "c"} {
+		logger.Info(msg)
+	}
+	if err := h.Flush(context.Background()); err != nil {
+		t.Fatalf("Flush() error = %v", err)
+	}
+	if got := messagesOf(down.seen()); len(got) != 3 || got[0] != "a" || got[2] != "c" {
+		t.Fatalf("written %q, want a, b, c in order", got)
+	}
+	if stats := h.Stats(); stats != (AsyncStats{}) {
+		t.Fatalf("Stats() = %+v, want zero", stats)
+	}
+}
+
+func Test_AsyncHandler_Overflow(t *testing.T) {
+	tests := []struct {
+		name string
+		opts AsyncOptions
+		last slog.Level
+		want []string
+	}{
+		{"drop newest", AsyncOptions{Overflow: OverflowDropNewest}, slog.LevelError, []string{"1", "2", "3"}},
+		{"drop oldest", AsyncOptions{Overflow: OverflowDropOldest}, slog.LevelInfo, []string{"1", "3", "4"}},
+		{"drop below level", AsyncOptions{Overflow: OverflowDropBelowLevel}, slog.LevelWarn, []string{"1", "2", "3"}},
+		{"drop below custom level", AsyncOptions{Overflow: OverflowDropBelowLevel, DropBelow: slog.LevelWarn}, slog.LevelInfo, []string{"1", "2", "3"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			down := gateHandler{recHandler: newRecHandler(LevelTrace), release: make(chan struct{})}
+			tt.opts.QueueSize = 2
+			h := NewAsyncHandler(down, tt.opts)
+			logger := slog.New(h)
+
+			logger.Info("1")
+			// the worker takes 1 and blocks on the gate, leaving room for two.
+			eventually(t, func() bool { return h.Stats().Queued == 0 })
+			logger.Info("2")
+			logger.Info("3")
+			logger.Log(context.Background(), tt.last, "4")
+
+			if stats := h.Stats(); stats.Dropped != 1 || stats.Queued != 2 {
+				t.Fatalf("Stats() = %+v, want 2 queued and 1 dropped", stats)
+			}
+			close(down.release)
+			if err := h.Close(context.Background()); err != nil {
+				t.Fatalf("Close() error = %v", err)
+			}
+			if got := messagesOf(down.seen()); len(got) != len(tt.want) || got[1] != tt.want[1] || got[2] != tt.want[2] {
+				t.Fatalf("written %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_AsyncHandler_Block(t *testing.T) {
+	down := gateHandler{recHandler: newRecHandler(LevelTrace), release: make(chan struct{})}
+	h := NewAsyncHandler(down, AsyncOptions{QueueSize: 1, Overflow: OverflowDropBelowLevel})
+	logger := slog.New(h)
+
+	logger.Error("1")
+	eventually(t, func() bool { return h.Stats().Queued == 0 })
+	logger.Error("2")
+
+	// errors are not dropped below the level, so a full queue blocks them.
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := h.Handle(ctx, newRecord(slog.LevelError, "3")); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Handle() on a full queue = %v, want the context's error", err)
+	}
+
+	written := make(chan struct{})
+	go func() {
+		logger.Error("4")
+		close(written)
+	}()
+	select {
+	case <-written:
+		t.Fatal("Error returned while the queue was full")
+	case <-time.After(20 * time.Millisecond):
+	}
+	close(down.release)
+	<-written
+
+	if err := h.Close(context.Background()); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+	if got := messagesOf(down.seen()); len(got) != 3 || got[2] != "4" {
+		t.Fatalf("written %q, want 1, 2, 4", got)
+	}
+	if dropped := h.Stats().Dropped; dropped != 1 {
+		t.Fatalf("Dropped = %d, want 1", dropped)
+	}
+}
+
+func Test_AsyncHandler_Close(t *testing.T) {
+	down := gateHandler{recHandler: newRecHandler(LevelTrace), release: make(chan struct{})}
+	h := NewAsyncHandler(down, AsyncOptions{})
+	logger := slog.New(h)
+	logger.Info("queued")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := h.Close(ctx); !errors.Is(err, context.Canceled) {
+		t.Fatalf("Close() with a stuck handler = %v, want the context's error", err)
+	}
+	logger.Info("after close")
+
+	close(down.release)
+	if err := h.Close(context.Background()); err != nil {
+		t.Fatalf("second Close() error = %v", err)
+	}
+	if got := messagesOf(down.seen()); len(got) != 1 || got[0] != "queued" {
+		t.Fatalf("written %q, want only the record queued before Close", got)
+	}
+	if dropped := h.Stats().Dropped; dropped != 1 {
+		t.Fatalf("Dropped = %d, want 1", dropped)
+	}
+}
+
+func Test_AsyncHandler_Failed(t *testing.T) {
+	h := NewAsyncHandler(newFakeHandler(LevelTrace, errors.New("disk full")), AsyncOptions{})
+	slog.New(h).Info("lost")
+	if err := h.Close(context.Background()); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+	if failed := h.Stats().Failed; failed != 1 {
+		t.Fatalf("Failed = %d, want 1", failed)
+	}
+}
+
+// countingValuer counts its LogValue calls and returns the count.
+type countingValuer struct{ calls *int }
+
+func (v countingValuer) LogValue() slog.Value {
+	*v.calls++
+	return slog.IntValue(*v.calls)
+}
+
+func Test_AsyncHandler_Handle_SnapshotsAttrs(t *testing.T) {
+	var buf lockedBuffer
+	release := make(chan struct{})
+	down := gateHandler{recHandler: newRecHandler(LevelTrace), release: release}
+	h := NewAsyncHandler(down, AsyncOptions{})
+	t.Cleanup(func() { _ = h.Close(context.Background()) })
+
+	ids := []string{"a", "b"}
+	payload := []byte("first")
+	calls := 0
+	slog.New(h).Info("batch", "ids", ids, "payload", payload, slog.Group("g", "n", countingValuer{&calls}))
+	ranBefore := calls
+	ids[0] = "changed"
+	copy(payload, "SECOND")
+	close(release)
+	if err := h.Flush(context.Background()); err != nil {
+		t.Fatalf("Flush() error = %v", err)
+	}
+
+	if ranBefore != 1 {
+		t.Fatalf("LogValue ran %d times before Handle returned, want 1", ranBefore)
+	}
+	seen := down.seen()
+	if len(seen) != 1 {
+		t.Fatalf("written %d records, want 1", len(seen))
+	}
+	_ = slog.NewJSONHandler(&buf, nil).Handle(context.Background(), seen[0])
+	if out := buf.String(); !strings.Contains(out, `"ids":["a","b"]`) || !strings.Contains(out, `"payload":"Zmlyc3Q="`) || !strings.Contains(out, `"g":{"n":1}`) {
+		t.Fatalf("written %s, want the attrs as they were when logged", out)
+	}
+}
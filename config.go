@@ -0,0 +1,798 @@
+package log
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"reflect"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Config is a declarative, JSON-decodable description of a Logger, for
+// NewFromConfig:
+//
+//	{
+//	  "level": "info",
+//	  "outputs": [
+//	    {"format": "text"},
+//	    {"format": "json", "path": "/var/log/app.log", "level": "debug"}
+//	  ],
+//	  "filters": [
+//	    {"action": "deny", "attrs": {"path": "/healthz*"}},
+//	    {"action": "shorten", "keys": ["body"], "limit": 200}
+//	  ],
+//	  "components": {"db": "warn", "db.pool": "trace"}
+//	}
+type Config struct {
+	// Level is the root level (default debug), parsed by ParseLevel.
+	Level string `json:"level,omitempty"`
+	// Source makes the outputs report the caller's file and line.
+	Source bool `json:"source,omitempty"`
+	// Outputs are the destinations; none means text to stdout.
+	Outputs []OutputConfig `json:"outputs,omitempty"`
+	// Filters run in order over every record, as with WithFilters.
+	Filters []FilterConfig `json:"filters,omitempty"`
+	// Components maps dotted component names to level overrides for Named
+	// loggers.
+	Components map[string]string `json:"components,omitempty"`
+}
+
+// OutputConfig describes one output of a Config.
+type OutputConfig struct {
+	// Format is "text" (default) or "json".
+	Format string `json:"format,omitempty"`
+	// Path is "stdout" (default), "stderr", or a file opened for appending.
+	Path string `json:"path,omitempty"`
+	// Level is an optional floor for this output only, on top of the root
+	// level and component overrides.
+	Level string `json:"level,omitempty"`
+}
+
+// FilterConfig describes one Filter of a Config. The criteria mirror the
+// Filter builder methods of the same names.
+type FilterConfig struct {
+	// Action is "deny", "allow", "shorten", "redact", "hash", "rename",
+	// "drop", "add", or "relevel".
+	Action string `json:"action"`
+	// Message matches the record message, as Filter.Message.
+	Message string `json:"message,omitempty"`
+	// MessageRegexp matches the record message, as Filter.MessageRegexp.
+	MessageRegexp string `json:"message_regexp,omitempty"`
+	// Attrs match attribute values, as Filter.Attr.
+	Attrs map[string]string `json:"attrs,omitempty"`
+	// AttrsRegexp match attribute values, as Filter.AttrRegexp.
+	AttrsRegexp map[string]string `json:"attrs_regexp,omitempty"`
+	// GT, GTE, LT and LTE compare attribute values, as Filter.AttrGT and
+	// friends, e.g. {"gt": {"status": 499}}. Strings that parse as a Go
+	// duration ("500ms") or an RFC 3339 time compare as one.
+	GT  map[string]any `json:"gt,omitempty"`
+	GTE map[string]any `json:"gte,omitempty"`
+	LT  map[string]any `json:"lt,omitempty"`
+	LTE map[string]any `json:"lte,omitempty"`
+	// In matches attributes equal to one of a list, as Filter.AttrIn. Strings
+	// match as strings; a duration or a time is written as {"duration": "5m"}
+	// or {"time": "2024-01-01T00:00:00Z"}, which GT and friends accept too.
+	In map[string][]any `json:"in,omitempty"`
+	// Exists and Missing name attributes that must be present or absent, as
+	// Filter.AttrExists and Filter.AttrMissing.
+	Exists  []string `json:"exists,omitempty"`
+	Missing []string `json:"missing,omitempty"`
+	// Level, Above, AtLeast and Below match record levels, as the Filter
+	// methods of the same names; set together, each must hold.
+	Level   string `json:"level,omitempty"`
+	Above   string `json:"above,omitempty"`
+	AtLeast string `json:"at_least,omitempty"`
+	Below   string `json:"below,omitempty"`
+	// Keys are the attributes to shorten, redact, hash or drop; shorten,
+	// redact, hash and drop only.
+	Keys []string `json:"keys,omitempty"`
+	// Limit is the shorten length limit (default 100) or the hash pseudonym
+	// length (default 16); shorten and hash only.
+	Limit int `json:"limit,omitempty"`
+	// CountTruncated notes how much was cut, as Filter.CountTruncated;
+	// shorten only.
+	CountTruncated bool `json:"count_truncated,omitempty"`
+	// Mask replaces redacted values (default "[REDACTED]"); redact only.
+	Mask string `json:"mask,omitempty"`
+	// Patterns name the secrets to mask wherever they appear: "emails",
+	// "bearer_tokens" and "card_numbers", as the Filter methods of the same
+	// names; redact only.
+	Patterns []string `json:"patterns,omitempty"`
+	// PatternsRegexp are further regular expressions to mask, as
+	// Filter.ValuesMatching; redact only.
+	PatternsRegexp []string `json:"patterns_regexp,omitempty"`
+	// KeyEnv names the environment variable holding the hash secret, so the
+	// secret itself stays out of the config; required for hash, hash only.
+	KeyEnv string `json:"key_env,omitempty"`
+	// From is the attribute to rename; rename only.
+	From string `json:"from,omitempty"`
+	// To is the new key for rename, or the new level for relevel; rename and
+	// relevel only.
+	To string `json:"to,omitempty"`
+	// Add are the attributes to add, as Filter.AddAttrs; add only.
+	Add map[string]any `json:"add,omitempty"`
+	// All, Any and Not nest criteria as Filter.Where with All, Any and Not:
+	// every entry of All must match, at least one of Any, and none of Not.
+	// Nested entries take criteria only, no action or action fields.
+	All []FilterConfig `json:"all,omitempty"`
+	Any []FilterConfig `json:"any,omitempty"`
+	Not []FilterConfig `json:"not,omitempty"`
+}
+
+// ConfigError reports an invalid Config field, named by its JSON path such as
+// "outputs[1].level".
+type ConfigError struct {
+	Field string
+	Err   error
+}
+
+// Error implements error.
+func (e *ConfigError) Error() string {
+	return fmt.Sprintf("log: config %s: %v", e.Field, e.Err)
+}
+
+// Unwrap returns the underlying error.
+func (e *ConfigError) Unwrap() error { return e.Err }
+
+// ReadConfig decodes a JSON Config from r, rejecting unknown fields so a
+// misspelled key is an error rather than silently ignored.
+func ReadConfig(r io.Reader) (Config, error) {
+	var cfg Config
+	dec := json.NewDecoder(r)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&cfg); err != nil {
+		return Config{}, fmt.Errorf("log: decode config: %w", err)
+	}
+	return cfg, nil
+}
+
+// NewFromConfig validates cfg and assembles a Logger from it, mapping it onto
+// the same options New takes. The logger always has a FilterHandler, even with
+// no filters configured, so filters can be swapped in later. Errors are
+// *ConfigError values naming the offending field; nothing is opened when
+// validation fails.
+func NewFromConfig(cfg Config) (Logger, error) {
+	opts, err := cfg.options()
+	if err != nil {
+		return nil, err
+	}
+
+	// open files only once everything else is known to be valid.
+	opened := make([]io.Writer, 0, len(cfg.Outputs))
+	for i, out := range cfg.Outputs {
+		w, err := openOutput(out.Path)
+		if err != nil {
+			closeOutputs(opened)
+			return nil, &ConfigError{Field: fmt.Sprintf("outputs[%d].path", i), Err: err}
+		}
+		opened = append(opened, w)
+		opts = append(opts, configOutput(w, out, cfg.Source))
+	}
+
+	return New(opts...), nil
+}
+
+// closeOutputs closes the files among outputs opened by openOutput, leaving
+// stdout and stderr open.
+func closeOutputs(outputs []io.Writer) {
+	for _, w := range outputs {
+		if f, ok := w.(*os.File); ok && f != os.Stdout && f != os.Stderr {
+			// the open error is the one worth reporting.
+			_ = f.Close()
+		}
+	}
+}
+
+// options validates everything but output paths and returns the matching
+// options, outputs excluded.
+func (cfg Config) options() ([]Option, error) {
+	var opts []Option
+
+	if cfg.Level != "" {
+		level, err := ParseLevel(cfg.Level)
+		if err != nil {
+			return nil, &ConfigError{Field: "level", Err: err}
+		}
+		opts = append(opts, WithLevel(level))
+	}
+	if cfg.Source {
+		opts = append(opts, WithSource())
+	}
+
+	for i, out := range cfg.Outputs {
+		switch strings.ToLower(out.Format) {
+		case "", "text", "json":
+		default:
+			return nil, &ConfigError{
+				Field: fmt.Sprintf("outputs[%d].format", i),
+				Err:   fmt.Errorf("unknown format %q, want text or json", out.Format),
+			}
+		}
+		if out.Level != "" {
+			if _, err := ParseLevel(out.Level); err != nil {
+				return nil, &ConfigError{Field: fmt.Sprintf("outputs[%d].level", i), Err: err}
+			}
+		}
+	}
+
+	filters, err := cfg.filters()
+	if err != nil {
+		return nil, err
+	}
+	opts = append(opts, WithFilters(filters...))
+
+	components := make(map[string]slog.Level, len(cfg.Components))
+	for name, value := range cfg.Components {
+		level, err := ParseLevel(value)
+		if err != nil {
+			return nil, &ConfigError{Field: fmt.Sprintf("components[%q]", name), Err: err}
+		}
+		components[name] = level
+	}
+	opts = append(opts, func(b *builder) { b.applyLevelSpec(LevelSpec{Components: components}) })
+
+	return opts, nil
+}
+
+// filters validates and builds the configured filters.
+func (cfg Config) filters() ([]Filter, error) {
+	filters := make([]Filter, 0, len(cfg.Filters))
+	for i, fc := range cfg.Filters {
+		filter, err := fc.filter()
+		if err != nil {
+			err.Field = fmt.Sprintf("filters[%d].%s", i, err.Field)
+			return nil, err
+		}
+		filters = append(filters, filter)
+	}
+	return filters, nil
+}
+
+// filter builds the Filter fc describes. The error's Field is relative to fc.
+func (fc FilterConfig) filter() (Filter, *ConfigError) {
+	var f Filter
+	action := strings.ToLower(fc.Action)
+	switch action {
+	case "deny":
+		f = Deny()
+	case "allow":
+		f = Allow()
+	case "shorten":
+		if len(fc.Keys) == 0 {
+			return Filter{}, &ConfigError{Field: "keys", Err: errors.New("shorten needs at least one key")}
+		}
+		f = Shorten(fc.Keys...)
+		if fc.Limit != 0 {
+			f = f.Limit(fc.Limit)
+		}
+		if fc.CountTruncated {
+			f = f.CountTruncated()
+		}
+	case "redact":
+		var err *ConfigError
+		if f, err = fc.redact(); err != nil {
+			return Filter{}, err
+		}
+	case "hash":
+		var err *ConfigError
+		if f, err = fc.hash(); err != nil {
+			return Filter{}, err
+		}
+	case "rename":
+		if fc.From == "" {
+			return Filter{}, &ConfigError{Field: "from", Err: errors.New("rename needs the key to rename")}
+		}
+		if fc.To == "" {
+			return Filter{}, &ConfigError{Field: "to", Err: errors.New("rename needs the new key")}
+		}
+		f = Rename(fc.From, fc.To)
+	case "drop":
+		if len(fc.Keys) == 0 {
+			return Filter{}, &ConfigError{Field: "keys", Err: errors.New("drop needs at least one key")}
+		}
+		f = DropAttrs(fc.Keys...)
+	case "add":
+		if len(fc.Add) == 0 {
+			return Filter{}, &ConfigError{Field: "add", Err: errors.New("add needs at least one attribute")}
+		}
+		keys := make([]string, 0, len(fc.Add))
+		for key := range fc.Add {
+			keys = append(keys, key)
+		}
+		// sorted, so records get the attrs in a stable order.
+		slices.Sort(keys)
+		attrs := make([]slog.Attr, len(keys))
+		for i, key := range keys {
+			attrs[i] = slog.Any(key, fc.Add[key])
+		}
+		f = AddAttrs(attrs...)
+	case "relevel":
+		level, err := ParseLevel(fc.To)
+		if err != nil {
+			return Filter{}, &ConfigError{Field: "to", Err: err}
+		}
+		f = Relevel(level)
+	default:
+		return Filter{}, &ConfigError{
+			Field: "action",
+			Err: fmt.Errorf("unknown action %q, want deny, allow, shorten, redact, hash, rename, drop, add, or relevel",
+				fc.Action),
+		}
+	}
+	if err := fc.actionOnly(action); err != nil {
+		return Filter{}, err
+	}
+	return fc.criteria(f)
+}
+
+// redact builds the Redact filter fc describes, without its criteria.
+func (fc FilterConfig) redact() (Filter, *ConfigError) {
+	if len(fc.Keys) == 0 && len(fc.Patterns) == 0 && len(fc.PatternsRegexp) == 0 {
+		return Filter{}, &ConfigError{Field: "keys", Err: errors.New("redact needs keys or patterns")}
+	}
+	f := Redact(fc.Keys...)
+	if fc.Mask != "" {
+		f = f.Mask(fc.Mask)
+	}
+	for i, name := range fc.Patterns {
+		idx := slices.IndexFunc(builtinRedactors, func(r redactor) bool { return r.name == name })
+		if idx < 0 {
+			return Filter{}, &ConfigError{
+				Field: fmt.Sprintf("patterns[%d]", i),
+				Err:   fmt.Errorf("unknown pattern %q, want emails, bearer_tokens, or card_numbers", name),
+			}
+		}
+		f = f.withRedactor(builtinRedactors[idx])
+	}
+	for i, expr := range fc.PatternsRegexp {
+		re, err := regexp.Compile(expr)
+		if err != nil {
+			return Filter{}, &ConfigError{Field: fmt.Sprintf("patterns_regexp[%d]", i), Err: err}
+		}
+		f = f.ValuesMatching(re)
+	}
+	return f, nil
+}
+
+// hash builds the Hash filter fc describes, without its criteria.
+func (fc FilterConfig) hash() (Filter, *ConfigError) {
+	if len(fc.Keys) == 0 {
+		return Filter{}, &ConfigError{Field: "keys", Err: errors.New("hash needs at least one key")}
+	}
+	if fc.KeyEnv == "" {
+		return Filter{}, &ConfigError{Field: "key_env", Err: errors.New("hash needs key_env, the environment variable holding the secret key")}
+	}
+	secret := os.Getenv(fc.KeyEnv)
+	if secret == "" {
+		return Filter{}, &ConfigError{Field: "key_env", Err: fmt.Errorf("environment variable %s is not set", fc.KeyEnv)}
+	}
+	f := Hash([]byte(secret), fc.Keys...)
+	f.keyEnv = fc.KeyEnv
+	if fc.Limit != 0 {
+		f = f.Limit(fc.Limit)
+	}
+	return f, nil
+}
+
+// actionOnly rejects the fields that only make sense for other actions than
+// action, which is "" for nested criteria.
+func (fc FilterConfig) actionOnly(action string) *ConfigError {
+	fields := []struct {
+		name    string
+		set     bool
+		actions []string
+	}{
+		{"keys", len(fc.Keys) > 0, []string{"shorten", "redact", "hash", "drop"}},
+		{"limit", fc.Limit != 0, []string{"shorten", "hash"}},
+		{"count_truncated", fc.CountTruncated, []string{"shorten"}},
+		{"mask", fc.Mask != "", []string{"redact"}},
+		{"patterns", len(fc.Patterns) > 0, []string{"redact"}},
+		{"patterns_regexp", len(fc.PatternsRegexp) > 0, []string{"redact"}},
+		{"key_env", fc.KeyEnv != "", []string{"hash"}},
+		{"from", fc.From != "", []string{"rename"}},
+		{"to", fc.To != "", []string{"rename", "relevel"}},
+		{"add", len(fc.Add) > 0, []string{"add"}},
+	}
+	for _, field := range fields {
+		if field.set && !slices.Contains(field.actions, action) {
+			return &ConfigError{
+				Field: field.name,
+				Err:   fmt.Errorf("only valid for %s", strings.Join(field.actions, " and ")),
+			}
+		}
+	}
+	return nil
+}
+
+// criteria adds fc's match criteria to f. The error's Field is relative to fc.
+func (fc FilterConfig) criteria(f Filter) (Filter, *ConfigError) {
+	if fc.Message != "" {
+		f = f.Message(fc.Message)
+	}
+	for key, value := range fc.Attrs {
+		if key == "" {
+			return Filter{}, &ConfigError{Field: "attrs", Err: errors.New("empty attribute key")}
+		}
+		f = f.Attr(key, value)
+	}
+	if fc.MessageRegexp != "" {
+		re, err := regexp.Compile(fc.MessageRegexp)
+		if err != nil {
+			return Filter{}, &ConfigError{Field: "message_regexp", Err: err}
+		}
+		f = f.MessageRegexp(re)
+	}
+	for key, value := range fc.AttrsRegexp {
+		if key == "" {
+			return Filter{}, &ConfigError{Field: "attrs_regexp", Err: errors.New("empty attribute key")}
+		}
+		re, err := regexp.Compile(value)
+		if err != nil {
+			return Filter{}, &ConfigError{Field: fmt.Sprintf("attrs_regexp[%q]", key), Err: err}
+		}
+		f = f.AttrRegexp(key, re)
+	}
+	for _, op := range []struct {
+		field  string
+		values map[string]any
+		build  func(Filter, string, any) Filter
+	}{
+		{"gt", fc.GT, Filter.AttrGT},
+		{"gte", fc.GTE, Filter.AttrGTE},
+		{"lt", fc.LT, Filter.AttrLT},
+		{"lte", fc.LTE, Filter.AttrLTE},
+	} {
+		for key, raw := range op.values {
+			field := fmt.Sprintf("%s[%q]", op.field, key)
+			value, err := comparisonValue(raw)
+			if err == nil && value.Kind() == slog.KindBool {
+				err = errors.New("booleans can only be matched with in")
+			}
+			if err != nil {
+				return Filter{}, &ConfigError{Field: field, Err: err}
+			}
+			f = op.build(f, key, value)
+		}
+	}
+	for key, raws := range fc.In {
+		values := make([]any, len(raws))
+		for i, raw := range raws {
+			value, err := configValue(raw)
+			if err != nil {
+				return Filter{}, &ConfigError{Field: fmt.Sprintf("in[%q][%d]", key, i), Err: err}
+			}
+			values[i] = value
+		}
+		f = f.AttrIn(key, values...)
+	}
+	for _, key := range fc.Exists {
+		f = f.AttrExists(key)
+	}
+	for _, key := range fc.Missing {
+		f = f.AttrMissing(key)
+	}
+	for _, criterion := range []struct {
+		field string
+		value string
+		build func(Filter, slog.Level) Filter
+	}{
+		{"level", fc.Level, Filter.Level},
+		{"above", fc.Above, Filter.Above},
+		{"at_least", fc.AtLeast, Filter.AtLeast},
+		{"below", fc.Below, Filter.Below},
+	} {
+		if criterion.value == "" {
+			continue
+		}
+		level, err := ParseLevel(criterion.value)
+		if err != nil {
+			return Filter{}, &ConfigError{Field: criterion.field, Err: err}
+		}
+		f = criterion.build(f, level)
+	}
+
+	all, err := nestedCriteria("all", fc.All)
+	if err != nil {
+		return Filter{}, err
+	}
+	if len(all) > 0 {
+		f = f.Where(all...)
+	}
+	anyOf, err := nestedCriteria("any", fc.Any)
+	if err != nil {
+		return Filter{}, err
+	}
+	if len(anyOf) > 0 {
+		f = f.Where(Any(anyOf...))
+	}
+	none, err := nestedCriteria("not", fc.Not)
+	if err != nil {
+		return Filter{}, err
+	}
+	for _, criteria := range none {
+		f = f.Where(Not(criteria))
+	}
+	return f, nil
+}
+
+// nestedCriteria builds the criteria-only entries of the all/any/not list
+// named field.
+func nestedCriteria(field string, configs []FilterConfig) ([]Filter, *ConfigError) {
+	out := make([]Filter, 0, len(configs))
+	for i, fc := range configs {
+		criteria, err := fc.nested()
+		if err != nil {
+			err.Field = fmt.Sprintf("%s[%d].%s", field, i, err.Field)
+			return nil, err
+		}
+		out = append(out, criteria)
+	}
+	return out, nil
+}
+
+// nested builds a criteria-only Filter from fc.
+func (fc FilterConfig) nested() (Filter, *ConfigError) {
+	if fc.Action != "" {
+		return Filter{}, &ConfigError{Field: "action", Err: errors.New("only valid at the top level")}
+	}
+	if err := fc.actionOnly(""); err != nil {
+		return Filter{}, err
+	}
+	return fc.criteria(Match())
+}
+
+// configValue converts a decoded JSON value into a typed slog.Value for the
+// predicate criteria: a number, a bool, a string, or a {"duration": ...} or
+// {"time": ...} object.
+func configValue(raw any) (slog.Value, error) {
+	switch v := raw.(type) {
+	case float64:
+		return slog.Float64Value(v), nil
+	case bool:
+		return slog.BoolValue(v), nil
+	case string:
+		return slog.StringValue(v), nil
+	case map[string]any:
+		if len(v) == 1 {
+			if s, ok := v["duration"].(string); ok {
+				d, err := time.ParseDuration(s)
+				if err != nil {
+					return slog.Value{}, err
+				}
+				return slog.DurationValue(d), nil
+			}
+			if s, ok := v["time"].(string); ok {
+				t, err := time.Parse(time.RFC3339Nano, s)
+				if err != nil {
+					return slog.Value{}, err
+				}
+				return slog.TimeValue(t), nil
+			}
+		}
+	}
+	return slog.Value{}, fmt.Errorf("unsupported value %v, want a number, string, bool, or a duration or time object", raw)
+}
+
+// comparisonValue is configValue for GT and friends, which also read a string
+// that parses as a duration or an RFC 3339 time as one: ordering a string
+// against a duration is never what was meant.
+func comparisonValue(raw any) (slog.Value, error) {
+	if s, ok := raw.(string); ok {
+		if d, err := time.ParseDuration(s); err == nil {
+			return slog.DurationValue(d), nil
+		}
+		if t, err := time.Parse(time.RFC3339Nano, s); err == nil {
+			return slog.TimeValue(t), nil
+		}
+	}
+	return configValue(raw)
+}
+
+// valueConfig is the inverse of configValue.
+func valueConfig(v slog.Value) any {
+	switch v.Kind() {
+	case slog.KindDuration:
+		return map[string]any{"duration": v.Duration().String()}
+	case slog.KindTime:
+		return map[string]any{"time": v.Time().Format(time.RFC3339Nano)}
+	default:
+		return v.Any()
+	}
+}
+
+// comparisonConfig is the inverse of comparisonValue.
+func comparisonConfig(v slog.Value) any {
+	switch v.Kind() {
+	case slog.KindDuration:
+		return v.Duration().String()
+	case slog.KindTime:
+		return v.Time().Format(time.RFC3339Nano)
+	default:
+		return v.Any()
+	}
+}
+
+// configOutput returns the option for one validated output writing to w. Like
+// a Text or JSON output it follows the root level, and one with its own level
+// gets it as a further floor.
+func configOutput(w io.Writer, out OutputConfig, source bool) Option {
+	return func(b *builder) {
+		b.outputs = append(b.outputs, output{gated: true, build: func(opts *slog.HandlerOptions) slog.Handler {
+			if out.Level != "" {
+				// validated by options.
+				opts.Level, _ = ParseLevel(out.Level)
+			}
+			opts.AddSource = source
+			if strings.EqualFold(out.Format, "json") {
+				return slog.NewJSONHandler(w, opts)
+			}
+			return slog.NewTextHandler(w, opts)
+		}})
+	}
+}
+
+// describable reports why config cannot describe f, if it cannot: a Hash built
+// in code rather than from key_env has no key_env to describe, and the secret
+// is never written out.
+func (f Filter) describable() error {
+	if f.action == hash && f.keyEnv == "" {
+		return errors.New("hash filter has no key_env; it cannot be described without its secret")
+	}
+	return nil
+}
+
+// config describes f in the Config format, the inverse of FilterConfig.filter.
+// Check describable first.
+func (f Filter) config() FilterConfig {
+	fc := f.criteriaConfig()
+	switch f.action {
+	case allow:
+		fc.Action = "allow"
+	case deny:
+		fc.Action = "deny"
+	case shorten:
+		fc.Action = "shorten"
+		fc.Keys = f.keys
+		fc.Limit = f.limit
+		fc.CountTruncated = f.counted
+	case redact:
+		fc.Action = "redact"
+		fc.Keys = f.keys
+		if f.mask != defaultMask {
+			fc.Mask = f.mask
+		}
+		for _, r := range f.redactors {
+			if r.name != "" {
+				fc.Patterns = append(fc.Patterns, r.name)
+			} else {
+				fc.PatternsRegexp = append(fc.PatternsRegexp, r.re.String())
+			}
+		}
+	case hash:
+		// the secret is never described, only where a configured one came from.
+		fc.Action = "hash"
+		fc.Keys = f.keys
+		fc.Limit = f.limit
+		fc.KeyEnv = f.keyEnv
+	case rename:
+		fc.Action = "rename"
+		fc.From = f.keys[0]
+		fc.To = f.to
+	case drop:
+		fc.Action = "drop"
+		fc.Keys = f.keys
+	case add:
+		fc.Action = "add"
+		fc.Add = make(map[string]any, len(f.added))
+		for _, attr := range f.added {
+			fc.Add[attr.Key] = attr.Value.Any()
+		}
+	case relevel:
+		fc.Action = "relevel"
+		fc.To = levelString(f.relevel)
+	}
+	return fc
+}
+
+// criteriaConfig describes f's criteria, without its action.
+func (f Filter) criteriaConfig() FilterConfig {
+	var fc FilterConfig
+	if f.message != nil {
+		fc.Message = f.message.raw
+	}
+	if f.messageRegexp != nil {
+		fc.MessageRegexp = f.messageRegexp.String()
+	}
+	if len(f.attributes) > 0 {
+		fc.Attrs = make(map[string]string, len(f.attributes))
+		for key, pattern := range f.attributes {
+			fc.Attrs[key] = pattern.raw
+		}
+	}
+	if len(f.attrRegexps) > 0 {
+		fc.AttrsRegexp = make(map[string]string, len(f.attrRegexps))
+		for key, re := range f.attrRegexps {
+			fc.AttrsRegexp[key] = re.String()
+		}
+	}
+	for _, p := range f.predicates {
+		fc.predicate(p)
+	}
+	if r := f.levels; r != nil && r.min == r.max {
+		fc.Level = levelString(r.min)
+	} else if r != nil {
+		if r.min != minLevel {
+			fc.AtLeast = levelString(r.min)
+		}
+		if r.max != maxLevel {
+			fc.Below = levelString(r.max + 1)
+		}
+	}
+	for _, criteria := range f.all {
+		fc.where(criteria.criteriaConfig())
+	}
+	for _, criteria := range f.anyOf {
+		fc.Any = append(fc.Any, criteria.criteriaConfig())
+	}
+	for _, criteria := range f.none {
+		fc.Not = append(fc.Not, criteria.criteriaConfig())
+	}
+	return fc
+}
+
+// where adds nested criteria to fc as an entry of All, hoisting a bare
+// composite into fc's own lists when that keeps the meaning: All and Not
+// entries always (they are conjunctive), Any only while fc has none.
+func (fc *FilterConfig) where(nested FilterConfig) {
+	own := nested
+	own.All, own.Any, own.Not = nil, nil, nil
+	bare := reflect.DeepEqual(own, FilterConfig{})
+	if !bare || (len(nested.Any) > 0 && len(fc.Any) > 0) {
+		fc.All = append(fc.All, nested)
+		return
+	}
+	fc.All = append(fc.All, nested.All...)
+	fc.Any = append(fc.Any, nested.Any...)
+	fc.Not = append(fc.Not, nested.Not...)
+}
+
+// predicate describes p in fc.
+func (fc *FilterConfig) predicate(p attrPredicate) {
+	var values *map[string]any
+	switch p.op {
+	case opGT:
+		values = &fc.GT
+	case opGTE:
+		values = &fc.GTE
+	case opLT:
+		values = &fc.LT
+	case opLTE:
+		values = &fc.LTE
+	case opIn:
+		in := make([]any, len(p.values))
+		for i, v := range p.values {
+			in[i] = valueConfig(v)
+		}
+		if fc.In == nil {
+			fc.In = make(map[string][]any)
+		}
+		fc.In[p.key] = in
+		return
+	case opExists:
+		fc.Exists = append(fc.Exists, p.key)
+		return
+	case opMissing:
+		fc.Missing = append(fc.Missing, p.key)
+		return
+	}
+	if *values == nil {
+		*values = make(map[string]any)
+	}
+	(*values)[p.key] = comparisonConfig(p.values[0])
+}
@@ -0,0 +1,419 @@
+package log
+
+import (
+	"errors"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func Test_NewFromConfig_AssemblesOutputsFiltersAndComponents(t *testing.T) {
+	dir := t.TempDir()
+	jsonPath := filepath.Join(dir, "app.json")
+	textPath := filepath.Join(dir, "app.txt")
+
+	cfg, err := ReadConfig(strings.NewReader(`{
+		"level": "info",
+		"outputs": [
+			{"format": "json", "path": "` + jsonPath + `"},
+			{"format": "text", "path": "` + textPath + `", "level": "warn"}
+		],
+		"filters": [
+			{"action": "deny", "attrs": {"path": "/healthz*"}},
+			{"action": "shorten", "keys": ["body"], "limit": 5}
+		],
+		"components": {"db": "debug"}
+	}`))
+	if err != nil {
+		t.Fatalf("ReadConfig() error = %v", err)
+	}
+	logger, err := NewFromConfig(cfg)
+	if err != nil {
+		t.Fatalf("NewFromConfig() error = %v", err)
+	}
+
+	logger.Debug("root-debug")
+	logger.Named("db").Debug("db-debug")
+	logger.Info("probe", "path", "/healthz")
+	logger.Info("resp", "body", "0123456789")
+	logger.Warn("warned")
+
+	jsonOut := readFile(t, jsonPath)
+	for _, want := range []string{"db-debug", `"body":"01..."`, "warned"} {
+		if !strings.Contains(jsonOut, want) {
+			t.Fatalf("json output missing %s: %q", want, jsonOut)
+		}
+	}
+	for _, unwanted := range []string{"root-debug", "probe"} {
+		if strings.Contains(jsonOut, unwanted) {
+			t.Fatalf("json output has %s: %q", unwanted, jsonOut)
+		}
+	}
+
+	// the text output has its own warn floor.
+	textOut := readFile(t, textPath)
+	if strings.Contains(textOut, "db-debug") || strings.Contains(textOut, "resp") {
+		t.Fatalf("text output ignored its level: %q", textOut)
+	}
+	if !strings.Contains(textOut, "warned") {
+		t.Fatalf("text output missing the warn record: %q", textOut)
+	}
+}
+
+func Test_NewFromConfig_ErrorsNameTheField(t *testing.T) {
+	t.Setenv("LOG_TEST_HASH_KEY", "s3cr3t")
+	tests := []struct {
+		name      string
+		cfg       Config
+		wantField string
+	}{
+		{"root level", Config{Level: "loud"}, "level"},
+		{"output format", Config{Outputs: []OutputConfig{{}, {Format: "xml"}}}, "outputs[1].format"},
+		{"output level", Config{Outputs: []OutputConfig{{Level: "loud"}}}, "outputs[0].level"},
+		{"output path", Config{Outputs: []OutputConfig{{Path: "/nonexistent/dir/app.log"}}}, "outputs[0].path"},
+		{"filter action", Config{Filters: []FilterConfig{{Action: "deny"}, {Action: "discard"}}}, "filters[1].action"},
+		{"filter below", Config{Filters: []FilterConfig{{Action: "deny", Below: "loud"}}}, "filters[0].below"},
+		{"filter at_least", Config{Filters: []FilterConfig{{Action: "deny", AtLeast: "loud"}}}, "filters[0].at_least"},
+		{"shorten without keys", Config{Filters: []FilterConfig{{Action: "shorten"}}}, "filters[0].keys"},
+		{"keys on deny", Config{Filters: []FilterConfig{{Action: "deny", Keys: []string{"x"}}}}, "filters[0].keys"},
+		{"limit on allow", Config{Filters: []FilterConfig{{Action: "allow", Limit: 3}}}, "filters[0].limit"},
+		{"empty attr key", Config{Filters: []FilterConfig{{Action: "deny", Attrs: map[string]string{"": "x"}}}}, "filters[0].attrs"},
+		{"message regexp", Config{Filters: []FilterConfig{{Action: "deny", MessageRegexp: "("}}}, "filters[0].message_regexp"},
+		{"attr regexp", Config{Filters: []FilterConfig{{Action: "deny", AttrsRegexp: map[string]string{"path": "["}}}}, `filters[0].attrs_regexp["path"]`},
+		{"gt bool", Config{Filters: []FilterConfig{{Action: "deny", GT: map[string]any{"ok": true}}}}, `filters[0].gt["ok"]`},
+		{"lt object", Config{Filters: []FilterConfig{{Action: "deny", LT: map[string]any{"x": map[string]any{}}}}}, `filters[0].lt["x"]`},
+		{"in null", Config{Filters: []FilterConfig{{Action: "deny", In: map[string][]any{"status": {500.0, nil}}}}}, `filters[0].in["status"][1]`},
+		{"redact without keys", Config{Filters: []FilterConfig{{Action: "redact"}}}, "filters[0].keys"},
+		{"unknown pattern", Config{Filters: []FilterConfig{{Action: "redact", Patterns: []string{"emails", "ssn"}}}}, "filters[0].patterns[1]"},
+		{"bad pattern regexp", Config{Filters: []FilterConfig{{Action: "redact", PatternsRegexp: []string{"("}}}}, "filters[0].patterns_regexp[0]"},
+		{"limit on redact", Config{Filters: []FilterConfig{{Action: "redact", Keys: []string{"x"}, Limit: 3}}}, "filters[0].limit"},
+		{"hash without keys", Config{Filters: []FilterConfig{{Action: "hash"}}}, "filters[0].keys"},
+		{"hash without key env", Config{Filters: []FilterConfig{{Action: "hash", Keys: []string{"x"}}}}, "filters[0].key_env"},
+		{"hash key env unset", Config{Filters: []FilterConfig{{Action: "hash", Keys: []string{"x"}, KeyEnv: "LOG_TEST_UNSET_HASH_KEY"}}}, "filters[0].key_env"},
+		{"key env on redact", Config{Filters: []FilterConfig{{Action: "redact", Keys: []string{"x"}, KeyEnv: "K"}}}, "filters[0].key_env"},
+		{"rename without from", Config{Filters: []FilterConfig{{Action: "rename", To: "b"}}}, "filters[0].from"},
+		{"rename without to", Config{Filters: []FilterConfig{{Action: "rename", From: "a"}}}, "filters[0].to"},
+		{"drop without keys", Config{Filters: []FilterConfig{{Action: "drop"}}}, "filters[0].keys"},
+		{"add without attrs", Config{Filters: []FilterConfig{{Action: "add"}}}, "filters[0].add"},
+		{"relevel level", Config{Filters: []FilterConfig{{Action: "relevel", To: "loud"}}}, "filters[0].to"},
+		{"to on deny", Config{Filters: []FilterConfig{{Action: "deny", To: "warn"}}}, "filters[0].to"},
+		{"count_truncated on hash", Config{Filters: []FilterConfig{{Action: "hash", Keys: []string{"x"}, KeyEnv: "LOG_TEST_HASH_KEY", CountTruncated: true}}}, "filters[0].count_truncated"},
+		{"mask on shorten", Config{Filters: []FilterConfig{{Action: "shorten", Keys: []string{"x"}, Mask: "*"}}}, "filters[0].mask"},
+		{"nested patterns", Config{Filters: []FilterConfig{{Action: "deny", All: []FilterConfig{{Patterns: []string{"emails"}}}}}}, "filters[0].all[0].patterns"},
+		{"component level", Config{Components: map[string]string{"db": "loud"}}, `components["db"]`},
+		{"nested action", Config{Filters: []FilterConfig{{Action: "deny", Any: []FilterConfig{{}, {Action: "deny"}}}}}, "filters[0].any[1].action"},
+		{"deeply nested level", Config{Filters: []FilterConfig{{Action: "deny", Not: []FilterConfig{{All: []FilterConfig{{Below: "loud"}}}}}}}, "filters[0].not[0].all[0].below"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewFromConfig(tt.cfg)
+			var cfgErr *ConfigError
+			if !errors.As(err, &cfgErr) {
+				t.Fatalf("NewFromConfig() error = %v, want a *ConfigError", err)
+			}
+			if cfgErr.Field != tt.wantField {
+				t.Fatalf("Field = %q, want %q", cfgErr.Field, tt.wantField)
+			}
+			if !strings.Contains(err.Error(), tt.wantField) {
+				t.Fatalf("error %q does not name the field %q", err, tt.wantField)
+			}
+		})
+	}
+}
+
+func Test_NewFromConfig_DoesNotOpenFilesWhenInvalid(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	_, err := NewFromConfig(Config{
+		Outputs: []OutputConfig{{Path: path}},
+		Filters: []FilterConfig{{Action: "discard"}},
+	})
+	if err == nil {
+		t.Fatal("NewFromConfig() error = nil, want an invalid action error")
+	}
+	if _, statErr := os.Stat(path); !errors.Is(statErr, os.ErrNotExist) {
+		t.Fatalf("output file was created despite the invalid config (stat error %v)", statErr)
+	}
+}
+
+func Test_closeOutputs(t *testing.T) {
+	w, err := openOutput(filepath.Join(t.TempDir(), "app.log"))
+	if err != nil {
+		t.Fatalf("openOutput() error = %v", err)
+	}
+	closeOutputs([]io.Writer{os.Stdout, w, os.Stderr})
+
+	if _, err := w.Write([]byte("x")); !errors.Is(err, os.ErrClosed) {
+		t.Fatalf("Write after closeOutputs error = %v, want os.ErrClosed", err)
+	}
+	if _, err := os.Stdout.Stat(); err != nil {
+		t.Fatalf("stdout was closed: %v", err)
+	}
+}
+
+func Test_ReadConfig_RejectsUnknownFields(t *testing.T) {
+	_, err := ReadConfig(strings.NewReader(`{"levle": "info"}`))
+	if err == nil || !strings.Contains(err.Error(), "levle") {
+		t.Fatalf("ReadConfig() error = %v, want it to name the unknown field", err)
+	}
+}
+
+func Test_NewFromConfig_DefaultsToDebugOnStdout(t *testing.T) {
+	logger, err := NewFromConfig(Config{})
+	if err != nil {
+		t.Fatalf("NewFromConfig() error = %v", err)
+	}
+	if got := logger.Level(); got != slog.LevelDebug {
+		t.Fatalf("Level() = %v, want %v", got, slog.LevelDebug)
+	}
+}
+
+func Test_NewFromConfig_CompositeFilters(t *testing.T) {
+	cfg, err := ReadConfig(strings.NewReader(`{
+		"filters": [
+			{"action": "deny", "below": "info", "not": [{"attrs": {"component": "auth"}}]},
+			{"action": "deny", "any": [{"attrs": {"path": "/healthz"}}, {"attrs": {"path": "/metrics"}}]}
+		]
+	}`))
+	if err != nil {
+		t.Fatalf("ReadConfig() error = %v", err)
+	}
+	filters, cfgErr := cfg.filters()
+	if cfgErr != nil {
+		t.Fatalf("filters() error = %v", cfgErr)
+	}
+
+	down := newRecHandler(LevelTrace)
+	logger := slog.New(NewFilterHandler(down, filters...))
+	logger.Debug("db-debug", "component", "db")
+	logger.Debug("auth-debug", "component", "auth")
+	logger.Info("healthz", "path", "/healthz")
+	logger.Info("metrics", "path", "/metrics")
+	logger.Info("api", "path", "/api")
+
+	var msgs []string
+	for _, r := range down.seen() {
+		msgs = append(msgs, r.Message)
+	}
+	if strings.Join(msgs, ",") != "auth-debug,api" {
+		t.Fatalf("kept %v, want [auth-debug api]", msgs)
+	}
+
+	// the filters describe themselves back in the same shape.
+	round, err := filterConfigs(filters)
+	if err != nil {
+		t.Fatalf("filterConfigs() error = %v", err)
+	}
+	if len(round[0].Not) != 1 || round[0].Not[0].Attrs["component"] != "auth" {
+		t.Fatalf("config() of the not filter = %+v", round[0])
+	}
+	if len(round[1].Any) != 2 || len(round[1].All) != 0 {
+		t.Fatalf("config() of the any filter = %+v", round[1])
+	}
+}
+
+func Test_NewFromConfig_TypedFilters(t *testing.T) {
+	cfg, err := ReadConfig(strings.NewReader(`{
+		"filters": [
+			{"action": "allow", "gte": {"status": 500}},
+			{"action": "allow", "gt": {"latency": "500ms"}},
+			{"action": "deny", "in": {"status": [200, 204]}, "missing": ["err"]}
+		]
+	}`))
+	if err != nil {
+		t.Fatalf("ReadConfig() error = %v", err)
+	}
+	filters, cfgErr := cfg.filters()
+	if cfgErr != nil {
+		t.Fatalf("filters() error = %v", cfgErr)
+	}
+
+	down := newRecHandler(LevelTrace)
+	logger := slog.New(NewFilterHandler(down, filters...))
+	logger.Info("failed", "status", 503, "latency", time.Millisecond)
+	logger.Info("slow", "status", 200, "latency", time.Second)
+	logger.Info("fast", "status", 200, "latency", time.Millisecond)
+	logger.Info("fast-err", "status", 204, "err", "eof")
+
+	var msgs []string
+	for _, r := range down.seen() {
+		msgs = append(msgs, r.Message)
+	}
+	if strings.Join(msgs, ",") != "failed,slow,fast-err" {
+		t.Fatalf("kept %v, want [failed slow fast-err]", msgs)
+	}
+
+	round, err := filterConfigs(filters)
+	if err != nil {
+		t.Fatalf("filterConfigs() error = %v", err)
+	}
+	if got := round[1].GT["latency"]; got != "500ms" {
+		t.Fatalf("config() of the duration filter gt = %v, want 500ms", got)
+	}
+	if len(round[2].In["status"]) != 2 || len(round[2].Missing) != 1 {
+		t.Fatalf("config() of the in filter = %+v", round[2])
+	}
+}
+
+func Test_NewFromConfig_InKeepsStrings(t *testing.T) {
+	cfg, err := ReadConfig(strings.NewReader(`{
+		"filters": [
+			{"action": "deny", "in": {"code": ["0", "1h"]}},
+			{"action": "deny", "in": {"timeout": [{"duration": "5m"}]}}
+		]
+	}`))
+	if err != nil {
+		t.Fatalf("ReadConfig() error = %v", err)
+	}
+	filters, cfgErr := cfg.filters()
+	if cfgErr != nil {
+		t.Fatalf("filters() error = %v", cfgErr)
+	}
+
+	down := newRecHandler(LevelTrace)
+	logger := slog.New(NewFilterHandler(down, filters...))
+	logger.Info("zero", "code", "0")
+	logger.Info("hour", "code", "1h")
+	logger.Info("timeout", "timeout", 5*time.Minute)
+	logger.Info("kept", "code", "2", "timeout", time.Minute)
+
+	if got := messagesOf(down.seen()); len(got) != 1 || got[0] != "kept" {
+		t.Fatalf("kept %v, want only [kept]", got)
+	}
+
+	round, err := filterConfigs(filters)
+	if err != nil {
+		t.Fatalf("filterConfigs() error = %v", err)
+	}
+	if got := round[0].In["code"]; !reflect.DeepEqual(got, []any{"0", "1h"}) {
+		t.Fatalf("config() of the string in = %v, want the strings", got)
+	}
+	if got := round[1].In["timeout"]; !reflect.DeepEqual(got, []any{map[string]any{"duration": "5m0s"}}) {
+		t.Fatalf("config() of the duration in = %v, want a duration object", got)
+	}
+	if _, err := round[1].filter(); err != nil {
+		t.Fatalf("the described duration in does not read back: %v", err)
+	}
+}
+
+func Test_Filter_config_Levels(t *testing.T) {
+	tests := []struct {
+		name   string
+		filter Filter
+		want   FilterConfig
+	}{
+		{"below", Deny().Below(slog.LevelInfo), FilterConfig{Action: "deny", Below: "INFO"}},
+		{"at least", Deny().AtLeast(slog.LevelWarn), FilterConfig{Action: "deny", AtLeast: "WARN"}},
+		{"above", Deny().Above(slog.LevelWarn), FilterConfig{Action: "deny", AtLeast: "WARN+1"}},
+		{"exact", Deny().Level(LevelFatal), FilterConfig{Action: "deny", Level: "FATAL"}},
+		{"between", Deny().Between(LevelTrace, slog.LevelDebug), FilterConfig{Action: "deny", AtLeast: "TRACE", Below: "DEBUG+1"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.filter.config()
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("config() = %+v, want %+v", got, tt.want)
+			}
+			back, err := got.filter()
+			if err != nil {
+				t.Fatalf("filter() error = %v", err)
+			}
+			if *back.levels != *tt.filter.levels {
+				t.Fatalf("round trip levels = %+v, want %+v", *back.levels, *tt.filter.levels)
+			}
+		})
+	}
+}
+
+func Test_NewFromConfig_Redact(t *testing.T) {
+	cfg, err := ReadConfig(strings.NewReader(`{
+		"filters": [
+			{"action": "redact", "keys": ["password"], "mask": "***",
+			 "patterns": ["emails"], "patterns_regexp": ["sk_live_\\w+"]}
+		]
+	}`))
+	if err != nil {
+		t.Fatalf("ReadConfig() error = %v", err)
+	}
+	filters, cfgErr := cfg.filters()
+	if cfgErr != nil {
+		t.Fatalf("filters() error = %v", cfgErr)
+	}
+
+	down := newRecHandler(LevelTrace)
+	slog.New(NewFilterHandler(down, filters...)).Info("key sk_live_abc for a@b.io", "password", "hunter2")
+	rec := down.seen()[0]
+	if rec.Message != "key *** for ***" || attrsOf(rec)["password"] != "***" {
+		t.Fatalf("record = %q %v, want everything masked", rec.Message, attrsOf(rec))
+	}
+
+	round := filters[0].config()
+	want := FilterConfig{
+		Action:         "redact",
+		Keys:           []string{"password"},
+		Mask:           "***",
+		Patterns:       []string{"emails"},
+		PatternsRegexp: []string{`sk_live_\w+`},
+	}
+	if !reflect.DeepEqual(round, want) {
+		t.Fatalf("config() = %+v, want %+v", round, want)
+	}
+}
+
+func Test_NewFromConfig_Hash(t *testing.T) {
+	t.Setenv("LOG_TEST_HASH_KEY", "s3cr3t")
+	cfg := Config{Filters: []FilterConfig{{Action: "hash", Keys: []string{"user_id"}, Limit: 8, KeyEnv: "LOG_TEST_HASH_KEY"}}}
+	filters, cfgErr := cfg.filters()
+	if cfgErr != nil {
+		t.Fatalf("filters() error = %v", cfgErr)
+	}
+
+	down := newRecHandler(LevelTrace)
+	slog.New(NewFilterHandler(down, filters...)).Info("login", "user_id", "u-42")
+	want := Hash([]byte("s3cr3t")).Limit(8).pseudonym("u-42")
+	if got := attrsOf(down.seen()[0])["user_id"]; got != want {
+		t.Fatalf("user_id = %q, want %q", got, want)
+	}
+
+	round := filters[0].config()
+	if !reflect.DeepEqual(round, cfg.Filters[0]) {
+		t.Fatalf("config() = %+v, want %+v", round, cfg.Filters[0])
+	}
+}
+
+func Test_NewFromConfig_Rewrites(t *testing.T) {
+	cfg, err := ReadConfig(strings.NewReader(`{
+		"filters": [
+			{"action": "rename", "from": "status_code", "to": "status"},
+			{"action": "drop", "keys": ["debug"]},
+			{"action": "add", "add": {"source": "thirdparty", "retry": true}},
+			{"action": "relevel", "to": "WARN", "level": "ERROR"}
+		]
+	}`))
+	if err != nil {
+		t.Fatalf("ReadConfig() error = %v", err)
+	}
+	filters, cfgErr := cfg.filters()
+	if cfgErr != nil {
+		t.Fatalf("filters() error = %v", cfgErr)
+	}
+
+	down := newRecHandler(LevelTrace)
+	slog.New(NewFilterHandler(down, filters...)).Error("failed", "status_code", 502, "debug", "x")
+	rec := down.seen()[0]
+	got := attrsOf(rec)
+	want := map[string]string{"status": "502", "retry": "true", "source": "thirdparty"}
+	if rec.Level != slog.LevelWarn || !reflect.DeepEqual(got, want) {
+		t.Fatalf("record = %v %v, want WARN %v", rec.Level, got, want)
+	}
+
+	for i, filter := range filters {
+		if round := filter.config(); !reflect.DeepEqual(round, cfg.Filters[i]) {
+			t.Fatalf("filters[%d].config() = %+v, want %+v", i, round, cfg.Filters[i])
+		}
+	}
+}
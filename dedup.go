@@ -0,0 +1,212 @@
+package log
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DedupHandler is a slog.Handler that collapses a run of identical records,
+// same level, message and attributes, into the first of them, with a
+// repeat_count attribute when it stood for more than one:
+//
+//	level=WARN msg="retrying upload" repeat_count=37
+//
+// A record is held until a different one arrives, the window from the first
+// record of its run has passed, or Flush is called, so output lags by up to the
+// window. Timestamps and source locations are those of the first record.
+//
+// Records at slog.LevelError and above are not held: the first of a run is
+// written at once, so a FATAL record logged just before the process exits is
+// not lost, and when the run ends a copy of it reports the repeats that
+// followed in repeat_count. Call Flush before exiting to write what is held.
+//
+// Runs are tracked across every handler derived with WithAttrs or WithGroup.
+// Records are written outside the handler's lock, so a slow wrapped handler
+// holds up only the goroutine writing to it; runs ended by concurrent
+// goroutines may come out in either order.
+type DedupHandler struct {
+	handler slog.Handler
+	state   *dedupState
+	// scope identifies the held attrs and groups, which records must share to
+	// be identical.
+	scope string
+}
+
+// dedupState is the run shared by derived DedupHandlers.
+type dedupState struct {
+	window time.Duration
+
+	mu  sync.Mutex
+	run *dedupRun
+	// deadline is when the current run is due to be written; timer fires at
+	// or after it and is reused from run to run.
+	deadline time.Time
+	timer    *time.Timer
+}
+
+// dedupRun is a run of identical records: the first of them, written to
+// handler, and how many there were. written is set when the first was written
+// through on arrival.
+type dedupRun struct {
+	record  slog.Record
+	handler slog.Handler
+	key     string
+	count   int
+	written bool
+}
+
+var _ slog.Handler = (*DedupHandler)(nil)
+
+// NewDedupHandler wraps handler, collapsing identical records that follow
+// each other within window (default 1s).
+func NewDedupHandler(handler slog.Handler, window time.Duration) *DedupHandler {
+	if window <= 0 {
+		window = time.Second
+	}
+	return &DedupHandler{handler: handler, state: &dedupState{window: window}}
+}
+
+// Enabled reports whether the wrapped handler is enabled at level.
+func (h *DedupHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.handler.Enabled(ctx, level)
+}
+
+// Handle counts record against the current run if it is identical, and
+// otherwise writes the run out and starts a new one with record.
+func (h *DedupHandler) Handle(ctx context.Context, record slog.Record) error {
+	key := h.dedupKey(record)
+	through := record.Level >= slog.LevelError
+
+	s := h.state
+	s.mu.Lock()
+	if s.run != nil && s.run.key == key {
+		s.run.count++
+		s.mu.Unlock()
+		return nil
+	}
+	prev := s.take()
+	s.run = &dedupRun{record: record.Clone(), handler: h.handler, key: key, count: 1, written: through}
+	s.deadline = time.Now().Add(s.window)
+	if s.timer == nil {
+		s.timer = time.AfterFunc(s.window, s.expire)
+	} else {
+		s.timer.Reset(s.window)
+	}
+	s.mu.Unlock()
+
+	err := prev.write(ctx)
+	if through {
+		err = errors.Join(err, h.handler.Handle(ctx, record))
+	}
+	return err
+}
+
+// Flush writes out the current run now; call it before shutting down.
+func (h *DedupHandler) Flush(ctx context.Context) error {
+	s := h.state
+	s.mu.Lock()
+	run := s.take()
+	s.mu.Unlock()
+	return run.write(ctx)
+}
+
+// take removes and returns the current run, if any. The caller holds s.mu.
+func (s *dedupState) take() *dedupRun {
+	run := s.run
+	if run != nil {
+		s.run = nil
+		s.timer.Stop()
+	}
+	return run
+}
+
+// expire writes out the current run once its deadline has passed. It runs on
+// the timer, which may fire for a run that has since been replaced.
+func (s *dedupState) expire() {
+	s.mu.Lock()
+	if s.run == nil {
+		s.mu.Unlock()
+		return
+	}
+	if wait := time.Until(s.deadline); wait > 0 {
+		s.timer.Reset(wait)
+		s.mu.Unlock()
+		return
+	}
+	run := s.take()
+	s.mu.Unlock()
+	// no caller to return an error to.
+	_ = run.write(context.Background())
+}
+
+// write writes out run, if any: its first record with the run's size, or for
+// a run whose first record was written on arrival, a copy reporting the
+// repeats, if there were any.
+func (run *dedupRun) write(ctx context.Context) error {
+	if run == nil {
+		return nil
+	}
+	record, repeats := run.record, run.count
+	if run.written {
+		repeats--
+		if repeats == 0 {
+			return nil
+		}
+	}
+	if run.count > 1 {
+		record.AddAttrs(slog.Int("repeat_count", repeats))
+	}
+	return run.handler.Handle(ctx, record)
+}
+
+// dedupKey identifies what makes record identical to another: h's scope and
+// the record's level, message and attributes.
+func (h *DedupHandler) dedupKey(record slog.Record) string {
+	var b strings.Builder
+	b.WriteString(h.scope)
+	b.WriteByte(0)
+	b.WriteString(record.Level.String())
+	b.WriteByte(0)
+	b.WriteString(record.Message)
+	record.Attrs(func(attr slog.Attr) bool {
+		b.WriteByte(0)
+		writeAttr(&b, attr)
+		return true
+	})
+	return b.String()
+}
+
+// writeAttr writes attr as key=value, resolving its value.
+func writeAttr(b *strings.Builder, attr slog.Attr) {
+	b.WriteString(attr.Key)
+	b.WriteByte('=')
+	b.WriteString(attr.Value.Resolve().String())
+}
+
+// WithAttrs returns a DedupHandler sharing the same run around the wrapped
+// handler's WithAttrs.
+func (h *DedupHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	if len(attrs) == 0 {
+		return h
+	}
+	var b strings.Builder
+	b.WriteString(h.scope)
+	for _, attr := range attrs {
+		b.WriteByte(0)
+		writeAttr(&b, attr)
+	}
+	return &DedupHandler{handler: h.handler.WithAttrs(attrs), state: h.state, scope: b.String()}
+}
+
+// WithGroup returns a DedupHandler sharing the same run around the wrapped
+// handler's WithGroup.
+func (h *DedupHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	return &DedupHandler{handler: h.handler.WithGroup(name), state: h.state, scope: h.scope + "\x00[" + name}
+}
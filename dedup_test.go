@@ -0,0 +1,134 @@
+package log
+
+import (
+	"context"
+	"log/slog"
+	"testing"
+	"time"
+)
+
+func Test_DedupHandler_Handle(t *testing.T) {
+	down := newRecHandler(LevelTrace)
+	h := NewDedupHandler(down, time.Hour)
+	logger := slog.New(h)
+
+	for i := 0; i < 3; i++ {
+		logger.Warn("retrying", "attempt", "upload")
+	}
+	if n := len(down.seen()); n != 0 {
+		t.Fatalf("forwarded %d records during the run, want 0", n)
+	}
+	logger.Warn("retrying", "attempt", "download") // different attrs end the run
+	logger.Info("done")
+	logger.With("svc", "a").Info("done") // held attrs count too
+	logger.With("svc", "a").Info("done")
+	if err := h.Flush(context.Background()); err != nil {
+		t.Fatalf("Flush() error = %v", err)
+	}
+
+	seen := down.seen()
+	want := []struct {
+		msg, attempt, count string
+	}{
+		{"retrying", "upload", "3"},
+		{"retrying", "download", ""},
+		{"done", "", ""},
+		{"done", "", "2"},
+	}
+	if len(seen) != len(want) {
+		t.Fatalf("forwarded %d records, want %d", len(seen), len(want))
+	}
+	for i, w := range want {
+		got := attrsOf(seen[i])
+		if seen[i].Message != w.msg || got["attempt"] != w.attempt || got["repeat_count"] != w.count {
+			t.Fatalf("record %d = %q %v, want %q attempt=%q repeat_count=%q", i, seen[i].Message, got, w.msg, w.attempt, w.count)
+		}
+	}
+}
+
+func Test_DedupHandler_FlushesAfterWindow(t *testing.T) {
+	down := newRecHandler(LevelTrace)
+	logger := slog.New(NewDedupHandler(down, 20*time.Millisecond))
+
+	logger.Warn("boom")
+	logger.Warn("boom")
+	eventually(t, func() bool { return len(down.seen()) == 1 })
+	if got := attrsOf(down.seen()[0])["repeat_count"]; got != "2" {
+		t.Fatalf("repeat_count = %q, want 2", got)
+	}
+
+	// the next identical record starts a new run.
+	logger.Warn("boom")
+	eventually(t, func() bool { return len(down.seen()) == 2 })
+	if _, ok := attrsOf(down.seen()[1])["repeat_count"]; ok {
+		t.Fatal("a single record got a repeat_count")
+	}
+}
+
+func Test_DedupHandler_WritesErrorsThrough(t *testing.T) {
+	down := newRecHandler(LevelTrace)
+	h := NewDedupHandler(down, time.Hour)
+	logger := slog.New(h)
+
+	logger.Log(context.Background(), LevelFatal, "cannot start")
+	if seen := down.seen(); len(seen) != 1 || seen[0].Message != "cannot start" {
+		t.Fatalf("forwarded %v, want the FATAL record at once", messagesOf(seen))
+	}
+
+	logger.Error("boom")
+	logger.Error("boom")
+	logger.Error("boom")
+	if n := len(down.seen()); n != 2 {
+		t.Fatalf("forwarded %d records, want 2 (repeats held)", n)
+	}
+	if err := h.Flush(context.Background()); err != nil {
+		t.Fatalf("Flush() error = %v", err)
+	}
+
+	seen := down.seen()
+	if len(seen) != 3 || seen[2].Message != "boom" || attrsOf(seen[2])["repeat_count"] != "2" {
+		t.Fatalf("forwarded %v, want a closing boom with repeat_count=2", messagesOf(seen))
+	}
+	if _, ok := attrsOf(seen[1])["repeat_count"]; ok {
+		t.Fatal("the record written through got a repeat_count")
+	}
+}
+
+func Test_DedupHandler_WritesOutsideLock(t *testing.T) {
+	release := make(chan struct{})
+	down := gateHandler{recHandler: newRecHandler(LevelTrace), release: release}
+	h := NewDedupHandler(down, time.Hour)
+	logger := slog.New(h)
+
+	logger.Info("a")
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		logger.Info("b") // ends a's run and blocks writing it
+	}()
+	eventually(t, func() bool {
+		h.state.mu.Lock()
+		defer h.state.mu.Unlock()
+		return h.state.run != nil && h.state.run.record.Message == "b"
+	})
+
+	// a repeat of the held record is only counted, so it must not wait.
+	counted := make(chan struct{})
+	go func() {
+		logger.Info("b")
+		close(counted)
+	}()
+	select {
+	case <-counted:
+	case <-time.After(time.Second):
+		t.Fatal("a repeat waited for the slow wrapped handler")
+	}
+	close(release)
+	<-done
+	if err := h.Flush(context.Background()); err != nil {
+		t.Fatalf("Flush() error = %v", err)
+	}
+	if got := messagesOf(down.seen()); len(got) != 2 || got[1] != "b" || attrsOf(down.seen()[1])["repeat_count"] != "2" {
+		t.Fatalf("forwarded %v, want a then b with repeat_count=2", got)
+	}
+}
@@ -0,0 +1,168 @@
+package log
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// Environment variables read by FromEnv.
+const (
+	envLevel  = "LOG_LEVEL"
+	envFormat = "LOG_FORMAT"
+	envOutput = "LOG_OUTPUT"
+)
+
+// ParseLevel parses a level name, case-insensitively: TRACE, DEBUG, INFO,
+// WARN, ERROR or FATAL, optionally with an offset such as "INFO+2" or
+// "TRACE-1". Unlike slog.Level.UnmarshalText it knows this package's custom
+// levels.
+func ParseLevel(s string) (slog.Level, error) {
+	name := strings.ToUpper(strings.TrimSpace(s))
+	for base, custom := range map[string]slog.Level{"TRACE": LevelTrace, "FATAL": LevelFatal} {
+		rest, ok := strings.CutPrefix(name, base)
+		if !ok {
+			continue
+		}
+		if rest == "" {
+			return custom, nil
+		}
+		offset, err := strconv.Atoi(rest)
+		if err != nil || (rest[0] != '+' && rest[0] != '-') {
+			return 0, fmt.Errorf("log: invalid level %q", s)
+		}
+		return custom + slog.Level(offset), nil
+	}
+
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(name)); err != nil {
+		return 0, fmt.Errorf("log: invalid level %q", s)
+	}
+	return level, nil
+}
+
+// levelString names level the way ParseLevel reads it, using TRACE and FATAL
+// for the custom levels instead of slog's "DEBUG-4" and "ERROR+4".
+func levelString(level slog.Level) string {
+	if name, ok := levelNames[level]; ok {
+		return name
+	}
+	return level.String()
+}
+
+// LevelSpec is a parsed level specification; see ParseLevelSpec.
+type LevelSpec struct {
+	// Level is the root level, or nil when the spec sets only components.
+	Level *slog.Level
+	// Components maps dotted component names to their override.
+	Components map[string]slog.Level
+}
+
+// ParseLevelSpec parses a comma-separated level specification such as
+// "info,db=debug,http.client=trace": a bare level sets the root, and
+// name=level pairs set per-component overrides for Named loggers.
+func ParseLevelSpec(spec string) (LevelSpec, error) {
+	out := LevelSpec{Components: make(map[string]slog.Level)}
+	for _, part := range strings.Split(spec, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		name, value, isComponent := strings.Cut(part, "=")
+		if !isComponent {
+			level, err := ParseLevel(part)
+			if err != nil {
+				return LevelSpec{}, err
+			}
+			out.Level = &level
+			continue
+		}
+		name = strings.TrimSpace(name)
+		if name == "" {
+			return LevelSpec{}, fmt.Errorf("log: missing component name in %q", part)
+		}
+		level, err := ParseLevel(value)
+		if err != nil {
+			return LevelSpec{}, fmt.Errorf("log: component %s: %w", name, err)
+		}
+		out.Components[name] = level
+	}
+	return out, nil
+}
+
+// FromEnv configures the logger from the environment:
+//
+//	LOG_LEVEL=info,db=debug,http.client=trace  root level and component overrides
+//	LOG_FORMAT=json|text                       output format (default text)
+//	LOG_OUTPUT=stderr|stdout|/path/to/file     output destination (default stdout)
+//
+// When LOG_FORMAT or LOG_OUTPUT is set, the output they select replaces the
+// outputs configured so far, so FromEnv can follow other options as an
+// override. Unset variables leave the logger alone; invalid ones are skipped
+// and reported as a WARN record, whatever the level, once the logger is built.
+// A file named by LOG_OUTPUT is opened for appending and stays open for the
+// life of the process.
+func FromEnv() Option {
+	return func(b *builder) {
+		if spec, ok := os.LookupEnv(envLevel); ok {
+			parsed, err := ParseLevelSpec(spec)
+			if err != nil {
+				b.errs = append(b.errs, fmt.Errorf("%s: %w", envLevel, err))
+			} else {
+				b.applyLevelSpec(parsed)
+			}
+		}
+
+		format, hasFormat := os.LookupEnv(envFormat)
+		output, hasOutput := os.LookupEnv(envOutput)
+		if !hasFormat && !hasOutput {
+			return
+		}
+
+		w, err := openOutput(output)
+		if err != nil {
+			b.errs = append(b.errs, fmt.Errorf("%s: %w", envOutput, err))
+			return
+		}
+		b.outputs = nil
+		switch strings.ToLower(strings.TrimSpace(format)) {
+		case "", "text":
+			WithText(w)(b)
+		case "json":
+			WithJSON(w)(b)
+		default:
+			b.errs = append(b.errs, fmt.Errorf("%s: unknown format %q, want text or json", envFormat, format))
+			WithText(w)(b)
+		}
+	}
+}
+
+// applyLevelSpec sets the root level and component overrides from spec.
+func (b *builder) applyLevelSpec(spec LevelSpec) {
+	if spec.Level != nil {
+		b.level.Set(*spec.Level)
+	}
+	for name, level := range spec.Components {
+		b.components.Set(name, level)
+	}
+}
+
+// openOutput resolves an output destination: stdout (also the default),
+// stderr, or a file path opened for appending.
+func openOutput(dest string) (io.Writer, error) {
+	switch strings.ToLower(strings.TrimSpace(dest)) {
+	case "", "stdout":
+		return os.Stdout, nil
+	case "stderr":
+		return os.Stderr, nil
+	}
+	//nolint:gosec // the path is the operator's chosen log file.
+	f, err := os.OpenFile(dest, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+	if err != nil {
+		return nil, err
+	}
+	return f, nil
+}
@@ -0,0 +1,192 @@
+package log
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func Test_ParseLevel(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    slog.Level
+		wantErr bool
+	}{
+		{in: "trace", want: LevelTrace},
+		{in: "TRACE", want: LevelTrace},
+		{in: " Trace ", want: LevelTrace},
+		{in: "TRACE+1", want: LevelTrace + 1},
+		{in: "debug", want: slog.LevelDebug},
+		{in: "info", want: slog.LevelInfo},
+		{in: "INFO+2", want: slog.LevelInfo + 2},
+		{in: "warn", want: slog.LevelWarn},
+		{in: "error", want: slog.LevelError},
+		{in: "fatal", want: LevelFatal},
+		{in: "FATAL-2", want: LevelFatal - 2},
+		{in: "", wantErr: true},
+		{in: "verbose", wantErr: true},
+		{in: "TRACEY", wantErr: true},
+		{in: "FATAL2", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			got, err := ParseLevel(tt.in)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseLevel(%q) error = %v, wantErr %v", tt.in, err, tt.wantErr)
+			}
+			if !tt.wantErr && got != tt.want {
+				t.Fatalf("ParseLevel(%q) = %v, want %v", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_ParseLevelSpec(t *testing.T) {
+	info := slog.LevelInfo
+	tests := []struct {
+		name           string
+		spec           string
+		wantLevel      *slog.Level
+		wantComponents map[string]slog.Level
+		wantErr        bool
+	}{
+		{
+			name:           "root and components",
+			spec:           "info,db=debug,http.client=trace",
+			wantLevel:      &info,
+			wantComponents: map[string]slog.Level{"db": slog.LevelDebug, "http.client": LevelTrace},
+		},
+		{
+			name:           "components only",
+			spec:           " db = warn , ",
+			wantComponents: map[string]slog.Level{"db": slog.LevelWarn},
+		},
+		{
+			name:           "empty spec",
+			spec:           "",
+			wantComponents: map[string]slog.Level{},
+		},
+		{name: "bad root level", spec: "loud", wantErr: true},
+		{name: "bad component level", spec: "db=loud", wantErr: true},
+		{name: "missing component name", spec: "=debug", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseLevelSpec(tt.spec)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseLevelSpec(%q) error = %v, wantErr %v", tt.spec, err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if (got.Level == nil) != (tt.wantLevel == nil) || (got.Level != nil && *got.Level != *tt.wantLevel) {
+				t.Fatalf("Level = %v, want %v", got.Level, tt.wantLevel)
+			}
+			if len(got.Components) != len(tt.wantComponents) {
+				t.Fatalf("Components = %v, want %v", got.Components, tt.wantComponents)
+			}
+			for name, level := range tt.wantComponents {
+				if got.Components[name] != level {
+					t.Fatalf("Components = %v, want %v", got.Components, tt.wantComponents)
+				}
+			}
+		})
+	}
+}
+
+func Test_FromEnv_ConfiguresLevelFormatAndFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	t.Setenv("LOG_LEVEL", "warn,db=debug")
+	t.Setenv("LOG_FORMAT", "json")
+	t.Setenv("LOG_OUTPUT", path)
+
+	logger := New(FromEnv())
+	logger.Info("hidden")
+	logger.Named("db").Debug("db-debug")
+	logger.Warn("shown")
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("read log file: %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("log file has %d lines, want 2: %q", len(lines), data)
+	}
+	var msgs []string
+	for _, line := range lines {
+		var rec map[string]any
+		if err := json.Unmarshal([]byte(line), &rec); err != nil {
+			t.Fatalf("line is not JSON: %q (%v)", line, err)
+		}
+		msgs = append(msgs, rec["msg"].(string))
+	}
+	if msgs[0] != "db-debug" || msgs[1] != "shown" {
+		t.Fatalf("messages = %v, want [db-debug shown]", msgs)
+	}
+}
+
+func Test_FromEnv_LevelOnlyKeepsOtherOutputs(t *testing.T) {
+	t.Setenv("LOG_LEVEL", "error")
+
+	var buf bytes.Buffer
+	logger := New(WithText(&buf), FromEnv())
+	logger.Warn("hidden")
+	logger.Error("shown")
+
+	out := buf.String()
+	if strings.Contains(out, "hidden") || !strings.Contains(out, "shown") {
+		t.Fatalf("LOG_LEVEL did not apply to the existing output: %q", out)
+	}
+}
+
+func Test_FromEnv_ReportsInvalidValues(t *testing.T) {
+	t.Setenv("LOG_LEVEL", "loud")
+
+	var buf bytes.Buffer
+	logger := New(WithText(&buf), WithLevel(slog.LevelInfo), FromEnv())
+
+	out := buf.String()
+	if !strings.Contains(out, "level=WARN") || !strings.Contains(out, "LOG_LEVEL") {
+		t.Fatalf("invalid LOG_LEVEL was not reported: %q", out)
+	}
+	if got := logger.Level(); got != slog.LevelInfo {
+		t.Fatalf("Level() = %v, want %v (invalid spec must not change it)", got, slog.LevelInfo)
+	}
+}
+
+func Test_FromEnv_OutputReplacesEarlierOutputs(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	t.Setenv("LOG_FORMAT", "json")
+	t.Setenv("LOG_OUTPUT", path)
+
+	var buf bytes.Buffer
+	logger := New(WithText(&buf), FromEnv())
+	logger.Info("once")
+
+	if buf.Len() != 0 {
+		t.Fatalf("the replaced text output still wrote %q", buf.String())
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("read log file: %v", err)
+	}
+	if n := strings.Count(string(data), "once"); n != 1 {
+		t.Fatalf("log file has the record %d times, want once: %q", n, data)
+	}
+}
+
+func Test_FromEnv_ReportsInvalidValuesAtAnyLevel(t *testing.T) {
+	t.Setenv("LOG_LEVEL", "loud")
+
+	var buf bytes.Buffer
+	New(WithText(&buf), WithLevel(slog.LevelError), FromEnv())
+
+	if out := buf.String(); !strings.Contains(out, "level=WARN") || !strings.Contains(out, "LOG_LEVEL") {
+		t.Fatalf("invalid LOG_LEVEL was not reported under a strict level: %q", out)
+	}
+}
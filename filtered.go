@@ -3,109 +3,412 @@ package log
 import (
 	"context"
 	"log/slog"
+	"regexp"
+	"slices"
+	"strconv"
 	"strings"
-	"sync"
+	"unicode/utf8"
 )
 
 // filterAction is what a matching Filter does to a record.
 type filterAction int
 
 const (
-	// allow passes the record through unchanged (the zero value).
+	// allow passes the record through, exempt from later deny filters (the
+	// zero value).
 	allow filterAction = iota
 	// deny drops the record so no downstream handler sees it.
 	deny
 	// shorten truncates the values of the configured attribute keys.
 	shorten
+	// redact masks the values of the configured attribute keys and any
+	// secrets the filter's redactors find.
+	redact
+	// hash replaces the values of the configured attribute keys with a keyed
+	// HMAC prefix.
+	hash
+	// rename changes the key of the configured attribute.
+	rename
+	// drop removes the configured attribute keys.
+	drop
+	// add appends the filter's attrs.
+	add
+	// relevel changes the record level.
+	relevel
 )
 
 // Filter selects log records and decides what happens to them, built fluently:
 //
 //	log.Deny().Below(slog.LevelInfo)
 //	log.Deny().Attr("path", "/healthz*")
+//	log.Deny().Message("*timeout*")
 //	log.Shorten("body").Limit(200).Message("http response")
 //
-// A record must match every set criterion (level, message, attributes) for the
-// filter's action to apply; criteria left unset are ignored.
+// A record must match every set criterion (level, message, attributes, Where)
+// for the filter's action to apply; criteria left unset are ignored. Criteria
+// compose with Any, All and Not:
+//
+//	// deny debug unless component=auth
+//	log.Deny().Below(slog.LevelInfo).Where(log.Not(log.Match().Attr("component", "auth")))
+//	// deny if path=/healthz OR path=/metrics
+//	log.Deny().Where(log.Any(log.Match().Attr("path", "/healthz"), log.Match().Attr("path", "/metrics")))
 type Filter struct {
-	action      filterAction
-	message     string
-	attributes  map[string]string
-	level       *slog.Level
-	shortenKeys []string
-	limit       int
+	action        filterAction
+	message       *glob
+	messageRegexp *regexp.Regexp
+	attributes    map[string]glob
+	attrRegexps   map[string]*regexp.Regexp
+	predicates    []attrPredicate
+	levels        *levelRange
+	all           []Filter
+	anyOf         []Filter
+	none          []Filter
+	keys          []string
+	limit         int
+	counted       bool
+	mask          string
+	redactors     []redactor
+	hashKey       []byte
+	// keyEnv names the environment variable a configured Hash key came from,
+	// so the filter can describe itself without the secret.
+	keyEnv  string
+	to      string
+	added   []slog.Attr
+	relevel slog.Level
 }
 
 // Deny starts a filter that drops matching records.
 func Deny() Filter { return Filter{action: deny} }
 
-// Allow starts a filter that passes matching records through unchanged.
+// Allow starts a filter that passes matching records through, exempting them
+// from any Deny filters after it. Earlier Deny filters still apply, as do
+// later rewrites such as Shorten.
 func Allow() Filter { return Filter{action: allow} }
 
 // Shorten starts a filter that truncates the given attribute keys on matching
-// records. The default length limit is 100; change it with Limit.
+// records. The default limit is 100 bytes; change it with Limit. Values are cut
+// on a rune boundary, so the result stays valid UTF-8. A key naming a group
+// shortens each value in it, and the key "msg" shortens the record message.
 func Shorten(keys ...string) Filter {
-	return Filter{action: shorten, shortenKeys: keys, limit: 100}
+	return Filter{action: shorten, keys: keys, limit: 100}
+}
+
+// Match starts a criteria-only filter, for use with Where, Any, All and Not.
+// Its action is ignored there; on its own it is an Allow matching every record.
+func Match() Filter { return Filter{} }
+
+// Any matches when at least one of criteria matches. With no criteria it
+// matches nothing.
+func Any(criteria ...Filter) Filter {
+	if len(criteria) == 0 {
+		return Not(Match())
+	}
+	return Filter{anyOf: append([]Filter(nil), criteria...)}
+}
+
+// All matches when every one of criteria matches. With no criteria it matches
+// everything.
+func All(criteria ...Filter) Filter {
+	return Filter{all: append([]Filter(nil), criteria...)}
+}
+
+// Not matches when criteria does not.
+func Not(criteria Filter) Filter {
+	return Filter{none: []Filter{criteria}}
 }
 
-// Message matches records whose message equals msg exactly.
+// Where adds criteria, typically built with Match, Any, All or Not, that must
+// all match as well. Only their criteria count; their actions are ignored.
+func (f Filter) Where(criteria ...Filter) Filter {
+	f.all = append(append([]Filter(nil), f.all...), criteria...)
+	return f
+}
+
+// Message matches records whose message matches the glob pattern msg, in which
+// "*" stands for any run of characters: "*timeout*" matches messages containing
+// "timeout", "dial*" a prefix, "*failed" a suffix. Without a "*" the message
+// must equal msg exactly. An empty msg clears the criterion.
 func (f Filter) Message(msg string) Filter {
-	f.message = msg
+	if msg == "" {
+		f.message = nil
+		return f
+	}
+	g := compileGlob(msg)
+	f.message = &g
 	return f
 }
 
-// Attr matches when the record's attribute key equals val. A val ending in "*"
-// matches by prefix. The record message is available under the "msg" key.
+// MessageRegexp matches records whose message matches re.
+func (f Filter) MessageRegexp(re *regexp.Regexp) Filter {
+	f.messageRegexp = re
+	return f
+}
+
+// Attr matches when the record's attribute key matches the glob pattern val,
+// as for Message: "/api/*/health", "*timeout*". A val with a "*" never matches
+// a missing attribute. The record message is available under the "msg" key.
 func (f Filter) Attr(key, val string) Filter {
-	attrs := make(map[string]string, len(f.attributes)+1)
-	for k, v := range f.attributes {
-		attrs[k] = v
+	f.attributes = withEntry(f.attributes, key, compileGlob(val))
+	return f
+}
+
+// AttrRegexp matches when the record has attribute key and its value matches
+// re. Attr and AttrRegexp on the same key must both match.
+func (f Filter) AttrRegexp(key string, re *regexp.Regexp) Filter {
+	f.attrRegexps = withEntry(f.attrRegexps, key, re)
+	return f
+}
+
+// AttrGT matches when attribute key is greater than value, compared by
+// slog.Kind: numbers numerically, whether int, uint or float, so
+// AttrGT("status", 499) matches slog.Int("status", 503); time.Duration and
+// time.Time values chronologically; strings lexically. A missing attribute or
+// one whose kind cannot be compared with value never matches.
+func (f Filter) AttrGT(key string, value any) Filter {
+	return f.withPredicate(key, opGT, value)
+}
+
+// AttrGTE matches when attribute key is greater than or equal to value,
+// compared as for AttrGT.
+func (f Filter) AttrGTE(key string, value any) Filter {
+	return f.withPredicate(key, opGTE, value)
+}
+
+// AttrLT matches when attribute key is less than value, compared as for
+// AttrGT, e.g. AttrLT("latency", 500*time.Millisecond).
+func (f Filter) AttrLT(key string, value any) Filter {
+	return f.withPredicate(key, opLT, value)
+}
+
+// AttrLTE matches when attribute key is less than or equal to value, compared
+// as for AttrGT.
+func (f Filter) AttrLTE(key string, value any) Filter {
+	return f.withPredicate(key, opLTE, value)
+}
+
+// AttrIn matches when attribute key equals one of values, compared as for
+// AttrGT, plus booleans: AttrIn("status", 502, 503, 504).
+func (f Filter) AttrIn(key string, values ...any) Filter {
+	return f.withPredicate(key, opIn, values...)
+}
+
+// AttrExists matches records that have attribute key, whatever its value.
+func (f Filter) AttrExists(key string) Filter {
+	return f.withPredicate(key, opExists)
+}
+
+// AttrMissing matches records that do not have attribute key.
+func (f Filter) AttrMissing(key string) Filter {
+	return f.withPredicate(key, opMissing)
+}
+
+// withPredicate returns f with one more attribute predicate, converting values
+// with slog.AnyValue so they carry their slog.Kind.
+func (f Filter) withPredicate(key string, op predicateOp, values ...any) Filter {
+	p := attrPredicate{key: key, op: op, values: make([]slog.Value, len(values))}
+	for i, v := range values {
+		p.values[i] = slog.AnyValue(v)
 	}
-	attrs[key] = val
-	f.attributes = attrs
+	f.predicates = append(append([]attrPredicate(nil), f.predicates...), p)
 	return f
 }
 
+// withEntry returns a copy of m with key set to value, so filters derived from
+// a shared base never write to its map.
+func withEntry[V any](m map[string]V, key string, value V) map[string]V {
+	out := make(map[string]V, len(m)+1)
+	for k, v := range m {
+		out[k] = v
+	}
+	out[key] = value
+	return out
+}
+
 // Below matches records strictly below level (e.g. Below(Info) matches Debug
 // and Trace). Paired with Deny it acts as a level floor.
+//
+// Below, Above, AtLeast, Between and Level combine: a record must satisfy each
+// of them, so Above(Debug).Below(Warn) matches Info alone.
 func (f Filter) Below(level slog.Level) Filter {
-	f.level = &level
+	if level == minLevel {
+		return f.withLevels(maxLevel, minLevel)
+	}
+	return f.withLevels(minLevel, level-1)
+}
+
+// Above matches records strictly above level.
+func (f Filter) Above(level slog.Level) Filter {
+	if level == maxLevel {
+		return f.withLevels(maxLevel, minLevel)
+	}
+	return f.withLevels(level+1, maxLevel)
+}
+
+// AtLeast matches records at level or above.
+func (f Filter) AtLeast(level slog.Level) Filter {
+	return f.withLevels(level, maxLevel)
+}
+
+// Between matches records from lo to hi, both included, e.g.
+// Between(LevelTrace, slog.LevelDebug) for the verbose levels.
+func (f Filter) Between(lo, hi slog.Level) Filter {
+	return f.withLevels(lo, hi)
+}
+
+// Level matches records at exactly level.
+func (f Filter) Level(level slog.Level) Filter {
+	return f.withLevels(level, level)
+}
+
+// withLevels narrows f's level range to lo..hi.
+func (f Filter) withLevels(lo, hi slog.Level) Filter {
+	r := levelRange{min: minLevel, max: maxLevel}
+	if f.levels != nil {
+		r = *f.levels
+	}
+	r.min, r.max = max(r.min, lo), min(r.max, hi)
+	f.levels = &r
 	return f
 }
 
-// Limit sets the Shorten length limit.
+// levelRange is an inclusive range of levels; min > max matches nothing.
+type levelRange struct {
+	min, max slog.Level
+}
+
+// contains reports whether level is in r.
+func (r levelRange) contains(level slog.Level) bool {
+	return r.min <= level && level <= r.max
+}
+
+// Limit sets the Shorten length limit, or the Hash pseudonym length.
 func (f Filter) Limit(n int) Filter {
 	f.limit = n
 	return f
 }
 
+// CountTruncated makes a Shorten filter note how much it cut, as in
+// "0123456... (+9 bytes)". The note comes on top of the limit.
+func (f Filter) CountTruncated() Filter {
+	f.counted = true
+	return f
+}
+
 // FilterHandler is a slog.Handler that applies an ordered list of filters to
-// each record before passing it to a wrapped handler. Filters run in order; the
-// first Deny match drops the record, and Shorten matches rewrite attributes.
+// each record before passing it to a wrapped handler. Filters run in order and
+// the first matching Allow or Deny decides the record's fate: a Deny drops it,
+// an Allow keeps it regardless of later Deny filters. Rewriting filters such as
+// Shorten and Rename apply wherever they sit in the list, as long as the record
+// is kept, and later filters match the record as they left it:
+//
+//	// keep all payments records, drop everything else below Info
+//	log.WithFilters(
+//		log.Allow().Attr("component", "payments"),
+//		log.Deny().Below(slog.LevelInfo),
+//	)
+//
+// Attributes and groups added via WithAttrs/WithGroup are held by the
+// FilterHandler, so filters match and rewrite them like the record's own
+// attributes. Keys inside groups are qualified with the group path, e.g.
+// "http.path". They are also pushed into the wrapped handler, which gets
+// records no filter rewrote as they are; a rewritten record goes to the
+// wrapped handler without them instead, rebuilt around the rewritten copy.
+//
+// Handlers derived via WithAttrs/WithGroup share the filter list with the one
+// they came from, so AddFilter and SetFilters on any of them reach all of them.
+// Create one with NewFilterHandler.
 type FilterHandler struct {
 	handler slog.Handler
-	filters []Filter
-	mu      sync.RWMutex
+	// derived is handler with the held attrs and groups applied.
+	derived slog.Handler
+	// settings holds the plan, shared by every handler derived from the same
+	// NewFilterHandler and swapped whole, so Handle reads it without taking a
+	// lock.
+	settings *settingsVar
+	groups   []string
+	attrs    []scopedAttrs
+}
+
+// scopedAttrs is one WithAttrs call, remembered with the number of groups that
+// were open at the time so it can be nested back in place.
+type scopedAttrs struct {
+	depth int
+	attrs []slog.Attr
 }
 
 var _ slog.Handler = (*FilterHandler)(nil)
 
 // NewFilterHandler wraps handler with the given filters.
 func NewFilterHandler(handler slog.Handler, filters ...Filter) *FilterHandler {
-	return &FilterHandler{
-		handler: handler,
-		filters: filters,
+	return newFilterHandler(handler, newSettingsVar(), filters)
+}
+
+// newFilterHandler is NewFilterHandler keeping its plan in settings.
+func newFilterHandler(handler slog.Handler, settings *settingsVar, filters []Filter) *FilterHandler {
+	f := &FilterHandler{handler: handler, derived: handler, settings: settings}
+	f.SetFilters(filters)
+	return f
+}
+
+// emptyPlan stands in for the plan of a zero FilterHandler.
+var emptyPlan = newFilterPlan(nil)
+
+// currentPlan returns the compiled filters in effect.
+func (f *FilterHandler) currentPlan() *filterPlan {
+	if f.settings == nil {
+		return emptyPlan
 	}
+	return f.settings.Load().plan
 }
 
-// Enabled reports whether the wrapped handler emits records at level. Filters
-// are evaluated in Handle, not here, since they can match on message or attrs.
+// Enabled reports whether the wrapped handler emits records at level and the
+// filters do not drop every record at level, so callers skip building records
+// a level-only Deny would discard. Other filters are evaluated in Handle, since
+// they can match on message or attrs.
 func (f *FilterHandler) Enabled(ctx context.Context, level slog.Level) bool {
-	return f.handler.Enabled(ctx, level)
+	return !f.deniesLevel(level) && f.handler.Enabled(ctx, level)
+}
+
+// levelDenier is implemented by handlers that can tell a level is dropped
+// outright, so levelHandler can skip building those records even though its
+// own threshold overrides the wrapped handler's Enabled.
+type levelDenier interface {
+	deniesLevel(level slog.Level) bool
+}
+
+// deniesLevel reports whether every record at level is certain to be dropped:
+// a Deny with level criteria only covers level, and no Allow before it could
+// match at level and exempt the record.
+func (f *FilterHandler) deniesLevel(level slog.Level) bool {
+	for _, filter := range f.currentPlan().filters {
+		if filter.levels != nil && !filter.levels.contains(level) {
+			continue
+		}
+		switch filter.action {
+		case allow, relevel:
+			// an Allow may exempt the record, a Relevel move it elsewhere.
+			return false
+		case deny:
+			if filter.levelOnly() {
+				return true
+			}
+		default:
+			// rewrites leave the record's fate to later filters.
+		}
+	}
+	return false
 }
 
-// shortenMessage truncates msg to limit, using a trailing "..." when there is
-// room for it (limit > 3).
+// levelOnly reports whether f has no criteria besides its level range.
+func (f Filter) levelOnly() bool {
+	return f.message == nil && f.messageRegexp == nil &&
+		len(f.attributes) == 0 && len(f.attrRegexps) == 0 && len(f.predicates) == 0 &&
+		len(f.all) == 0 && len(f.anyOf) == 0 && len(f.none) == 0
+}
+
+// shortenMessage truncates msg to limit bytes, using a trailing "..." when
+// there is room for it (limit > 3). It never splits a multi-byte rune, so the
+// result may fall a few bytes short of limit.
 func shortenMessage(msg string, limit int) string {
 	if len(msg) <= limit {
 		return msg
@@ -114,128 +417,267 @@ func shortenMessage(msg string, limit int) string {
 		if limit < 0 {
 			return ""
 		}
-		return msg[:limit]
+		return msg[:runeStart(msg, limit)]
+	}
+	return msg[:runeStart(msg, limit-3)] + "..."
+}
+
+// runeStart returns the start of the rune of s that byte i falls in.
+func runeStart(s string, i int) int {
+	for i > 0 && !utf8.RuneStart(s[i]) {
+		i--
 	}
-	return msg[:limit-3] + "..."
+	return i
+}
+
+// shorten truncates s as shortenMessage does, adding the CountTruncated note.
+func (f Filter) shorten(s string) string {
+	short := shortenMessage(s, f.limit)
+	if !f.counted || len(short) == len(s) {
+		return short
+	}
+	kept := len(short)
+	if f.limit > 3 {
+		kept -= len("...")
+	}
+	return short + " (+" + strconv.Itoa(len(s)-kept) + " bytes)"
+}
+
+// shortenValue shortens a string value, or every string within a group;
+// other kinds are kept, as cutting a number or a time would only garble it.
+// Any values are shortened as their string form once it exceeds the limit.
+func (f Filter) shortenValue(v slog.Value) slog.Value {
+	v = v.Resolve()
+	switch v.Kind() {
+	case slog.KindGroup:
+		members := v.Group()
+		short := make([]slog.Attr, len(members))
+		for i, member := range members {
+			short[i] = slog.Attr{Key: member.Key, Value: f.shortenValue(member.Value)}
+		}
+		return slog.GroupValue(short...)
+	case slog.KindString, slog.KindAny:
+		if s := v.String(); len(s) > f.limit {
+			return slog.StringValue(f.shorten(s))
+		}
+	default:
+		// numbers, times and the like are kept whole.
+	}
+	return v
 }
 
 // Handle applies each matching filter to the record and forwards the result to
-// the wrapped handler. A matching Deny returns early and drops the record.
+// the wrapped handler. A matching Deny returns early and drops the record,
+// unless an earlier Allow matched.
 func (f *FilterHandler) Handle(ctx context.Context, record slog.Record) error {
-	f.mu.RLock()
-	defer f.mu.RUnlock()
+	return f.handle(ctx, record, f.currentPlan())
+}
+
+// handle is Handle with the filters of plan.
+func (f *FilterHandler) handle(ctx context.Context, record slog.Record, plan *filterPlan) error {
+
+	// values are the attrs the filters read, collected on first use and again
+	// after a filter rewrites them; the backing array stays on the stack for
+	// plans reading few keys.
+	var (
+		stack     [maxStackSlots]attrSlot
+		values    []attrSlot
+		collected bool
+	)
+	if len(plan.slots) <= maxStackSlots {
+		values = stack[:len(plan.slots)]
+	} else {
+		values = make([]attrSlot, len(plan.slots))
+	}
 
-	for _, filter := range f.filters {
-		if !f.matchesFilter(record, filter) {
+	// scope and attrs are the working copies of the held and record attrs; they
+	// stay nil until a filter rewrites something. record.Message and
+	// record.Level are rewritten in place.
+	var (
+		scope   []scopedAttrs
+		attrs   []slog.Attr
+		level   = record.Level
+		allowed bool
+	)
+	for i, filter := range plan.filters {
+		if allowed && filter.action == deny {
+			// the first allow/deny match already decided.
+			continue
+		}
+		if filter.levels != nil && !filter.levels.contains(record.Level) {
+			// cheap reject before collecting attrs.
+			continue
+		}
+		if !collected {
+			plan.collect(f, record, scope, attrs, values)
+			collected = true
+		}
+		if !plan.matches(record, filter, values) {
 			continue
 		}
 
 		switch filter.action {
 		case allow:
-			// pass through unchanged.
+			allowed = true
 
 		case deny:
 			return nil
 
-		case shorten:
-			// build a set of keys to shorten once per filter
-			if len(filter.shortenKeys) == 0 {
+		case relevel:
+			// later level criteria see the new level.
+			record.Level = filter.relevel
+
+		case add:
+			if scope == nil {
+				scope = f.copyScope()
+				attrs = recordAttrs(record)
+			}
+			attrs = append(attrs, filter.added...)
+			collected = false
+
+		case shorten, redact, hash, rename, drop:
+			rewrite := plan.rewriters[i]
+			if rewrite == nil {
 				break
 			}
-			keySet := make(map[string]struct{}, len(filter.shortenKeys))
-			for _, k := range filter.shortenKeys {
-				keySet[k] = struct{}{}
+			switch {
+			case filter.action == redact:
+				record.Message = filter.redactString(record.Message)
+			case filter.action == shorten && slices.Contains(filter.keys, "msg"):
+				record.Message = filter.shorten(record.Message)
 			}
 
-			// reconstruct the record, replacing (not duplicating) matching attrs
-			newRec := slog.NewRecord(record.Time, record.Level, record.Message, record.PC)
-			record.Attrs(func(attr slog.Attr) bool {
-				if _, ok := keySet[attr.Key]; ok {
-					// replace value with shortened text
-					newVal := shortenMessage(attr.Value.String(), filter.limit)
-					newRec.AddAttrs(slog.String(attr.Key, newVal))
-					return true
-				}
-				// keep original attr
-				newRec.AddAttrs(attr)
-				return true
-			})
+			if scope == nil {
+				scope = f.copyScope()
+				attrs = recordAttrs(record)
+			}
+			for i := range scope {
+				prefix := strings.Join(f.groups[:scope[i].depth], ".")
+				scope[i].attrs = rewriteAttrs(rewrite, prefix, scope[i].attrs)
+			}
+			attrs = rewriteAttrs(rewrite, strings.Join(f.groups, "."), attrs)
+			// later filters match the rewritten attrs.
+			collected = false
+		}
+	}
+
+	if record.Level != level && !f.handler.Enabled(ctx, record.Level) {
+		// relevelled to a level the wrapped handler discards.
+		return nil
+	}
+	if scope == nil {
+		// nothing rewritten: derived holds the attrs as they are.
+		return f.derived.Handle(ctx, record)
+	}
+
+	// reconstruct the record, replacing (not duplicating) rewritten attrs and
+	// nesting the held attrs and groups back around the record's own.
+	newRec := slog.NewRecord(record.Time, record.Level, record.Message, record.PC)
+	newRec.AddAttrs(nestAttrs(0, f.groups, scope, attrs)...)
+	return f.handler.Handle(ctx, newRec)
+}
+
+// copyScope returns a deep copy of the held attrs, safe to rewrite in place.
+func (f *FilterHandler) copyScope() []scopedAttrs {
+	scope := make([]scopedAttrs, len(f.attrs))
+	for i, s := range f.attrs {
+		scope[i] = scopedAttrs{depth: s.depth, attrs: append([]slog.Attr(nil), s.attrs...)}
+	}
+	return scope
+}
+
+// recordAttrs collects a record's attributes into a slice.
+func recordAttrs(record slog.Record) []slog.Attr {
+	attrs := make([]slog.Attr, 0, record.NumAttrs())
+	record.Attrs(func(attr slog.Attr) bool {
+		attrs = append(attrs, attr)
+		return true
+	})
+	return attrs
+}
 
-			// use the rebuilt record for downstream handler (no dupes)
-			record = newRec
+// nestAttrs rebuilds, from depth down, the attribute tree the wrapped handler
+// would have produced from the held WithAttrs/WithGroup calls, with the
+// record's own attrs in the innermost group. Empty groups are omitted, as slog
+// handlers do.
+func nestAttrs(depth int, groups []string, scope []scopedAttrs, attrs []slog.Attr) []slog.Attr {
+	var out []slog.Attr
+	for _, s := range scope {
+		if s.depth == depth {
+			out = append(out, s.attrs...)
 		}
 	}
+	if depth == len(groups) {
+		return append(out, attrs...)
+	}
+	if inner := nestAttrs(depth+1, groups, scope, attrs); len(inner) > 0 {
+		out = append(out, slog.Attr{Key: groups[depth], Value: slog.GroupValue(inner...)})
+	}
+	return out
+}
 
-	return f.handler.Handle(ctx, record)
+// qualify joins a group prefix and a key with a dot.
+func qualify(prefix, key string) string {
+	if prefix == "" {
+		return key
+	}
+	if key == "" {
+		return prefix
+	}
+	return prefix + "." + key
 }
 
-// WithAttrs returns a new FilterHandler sharing the same filters, with attrs
-// applied to the wrapped handler.
+// WithAttrs returns a new FilterHandler sharing the same filters that holds
+// attrs in the current group, so filters can see them.
 func (f *FilterHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
-	f.mu.RLock()
-	defer f.mu.RUnlock()
+	if len(attrs) == 0 {
+		return f
+	}
+	scope := make([]scopedAttrs, len(f.attrs), len(f.attrs)+1)
+	copy(scope, f.attrs)
 	return &FilterHandler{
-		handler: f.handler.WithAttrs(attrs),
-		filters: f.filters,
+		handler:  f.handler,
+		derived:  f.derived.WithAttrs(attrs),
+		settings: f.settings,
+		groups:   f.groups,
+		attrs:    append(scope, scopedAttrs{depth: len(f.groups), attrs: append([]slog.Attr(nil), attrs...)}),
 	}
 }
 
-// WithGroup returns a new FilterHandler sharing the same filters, with the
-// named group applied to the wrapped handler.
+// WithGroup returns a new FilterHandler sharing the same filters that nests
+// subsequent attributes under name.
 func (f *FilterHandler) WithGroup(name string) slog.Handler {
-	f.mu.RLock()
-	defer f.mu.RUnlock()
+	if name == "" {
+		return f
+	}
+	groups := make([]string, len(f.groups), len(f.groups)+1)
+	copy(groups, f.groups)
 	return &FilterHandler{
-		handler: f.handler.WithGroup(name),
-		filters: f.filters,
+		handler:  f.handler,
+		derived:  f.derived.WithGroup(name),
+		settings: f.settings,
+		groups:   append(groups, name),
+		attrs:    f.attrs,
 	}
 }
 
-// AddFilter appends a filter; safe to call concurrently with logging.
+// AddFilter appends a filter, recompiling the list; safe to call concurrently
+// with logging and other updates, and seen by every derived handler.
 func (f *FilterHandler) AddFilter(filter Filter) {
-	f.mu.Lock()
-	defer f.mu.Unlock()
-	f.filters = append(f.filters, filter)
+	f.settings.update(func(s *settings) {
+		n := len(s.plan.filters)
+		s.plan = newFilterPlan(append(s.plan.filters[:n:n], filter))
+	})
 }
 
-// SetFilters replaces the filter list; safe to call concurrently with logging.
+// SetFilters replaces the filter list, compiling it once; safe to call
+// concurrently with logging, and seen by every derived handler.
 func (f *FilterHandler) SetFilters(filters []Filter) {
-	f.mu.Lock()
-	defer f.mu.Unlock()
-	f.filters = filters
+	plan := newFilterPlan(append([]Filter(nil), filters...))
+	f.settings.update(func(s *settings) { s.plan = plan })
 }
 
-// matchesFilter reports whether record satisfies every set criterion of filter.
-func (f *FilterHandler) matchesFilter(record slog.Record, filter Filter) bool {
-	if filter.level != nil && *filter.level <= record.Level {
-		return false
-	}
-
-	if filter.message != "" && record.Message != filter.message {
-		return false
-	}
-
-	if len(filter.attributes) > 0 {
-		recordAttrs := make(map[string]string)
-		recordAttrs["msg"] = record.Message
-		record.Attrs(func(attr slog.Attr) bool {
-			recordAttrs[attr.Key] = attr.Value.String()
-			return true
-		})
-
-		for filterKey, filterValue := range filter.attributes {
-			recordValue, present := recordAttrs[filterKey]
-			if valuePrefix, isPrefix := strings.CutSuffix(filterValue, "*"); isPrefix {
-				if !present || !strings.HasPrefix(recordValue, valuePrefix) {
-					return false
-				}
-				continue
-			}
-			if recordValue != filterValue {
-				return false
-			}
-		}
-	}
-
-	return true
+// Filters returns a copy of the current filter list.
+func (f *FilterHandler) Filters() []Filter {
+	return append([]Filter(nil), f.currentPlan().filters...)
 }
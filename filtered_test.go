@@ -1,10 +1,15 @@
 package log
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
 	"log/slog"
+	"regexp"
+	"strings"
 	"sync"
 	"testing"
+	"time"
 )
 
 func Test_FilterHandler_matchesFilter(t *testing.T) {
@@ -110,6 +115,54 @@ func Test_FilterHandler_matchesFilter(t *testing.T) {
 			record: newRecord(slog.LevelInfo, "hi", "path", ""),
 			want:   true,
 		},
+		{
+			name:   "message glob matches a substring",
+			filter: Allow().Message("*timeout*"),
+			record: newRecord(slog.LevelInfo, "dial tcp: i/o timeout after 5s"),
+			want:   true,
+		},
+		{
+			name:   "message without a wildcard is exact",
+			filter: Allow().Message("timeout"),
+			record: newRecord(slog.LevelInfo, "i/o timeout"),
+			want:   false,
+		},
+		{
+			name:   "attr glob with an inner wildcard",
+			filter: Allow().Attr("path", "/api/*/health"),
+			record: newRecord(slog.LevelInfo, "hi", "path", "/api/v2/health"),
+			want:   true,
+		},
+		{
+			name:   "attr glob suffix",
+			filter: Allow().Attr("path", "*.png"),
+			record: newRecord(slog.LevelInfo, "hi", "path", "/img/logo.svg"),
+			want:   false,
+		},
+		{
+			name:   "message regexp",
+			filter: Allow().MessageRegexp(regexp.MustCompile(`^user \d+ logged in$`)),
+			record: newRecord(slog.LevelInfo, "user 42 logged in"),
+			want:   true,
+		},
+		{
+			name:   "attr regexp",
+			filter: Allow().AttrRegexp("status", regexp.MustCompile(`^5\d\d$`)),
+			record: newRecord(slog.LevelInfo, "hi", "status", "503"),
+			want:   true,
+		},
+		{
+			name:   "attr regexp does not match an absent key",
+			filter: Allow().AttrRegexp("status", regexp.MustCompile(`.*`)),
+			record: newRecord(slog.LevelInfo, "hi"),
+			want:   false,
+		},
+		{
+			name:   "attr glob and regexp on one key must both match",
+			filter: Allow().Attr("path", "/api/*").AttrRegexp("path", regexp.MustCompile(`/v1/`)),
+			record: newRecord(slog.LevelInfo, "hi", "path", "/api/v2/users"),
+			want:   false,
+		},
 		{
 			name:   "all criteria must match (one fails)",
 			filter: Allow().Message("ping").Attr("user", "bob"),
@@ -135,6 +188,156 @@ func Test_FilterHandler_matchesFilter(t *testing.T) {
 	}
 }
 
+func Test_FilterHandler_matchesFilter_Typed(t *testing.T) {
+	at := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	tests := []struct {
+		name   string
+		filter Filter
+		attr   slog.Attr
+		want   bool
+	}{
+		{"int greater", Allow().AttrGT("latency_ms", 500), slog.Int("latency_ms", 750), true},
+		{"int not greater", Allow().AttrGT("latency_ms", 500), slog.Int("latency_ms", 500), false},
+		{"int at least", Allow().AttrGTE("status", 500), slog.Int("status", 500), true},
+		{"uint against int", Allow().AttrGTE("status", 500), slog.Uint64("status", 503), true},
+		{"float against int", Allow().AttrLT("ratio", 1), slog.Float64("ratio", 0.5), true},
+		{"int less or equal", Allow().AttrLTE("n", 3), slog.Int("n", 4), false},
+		{"string is not a number", Allow().AttrGT("status", 499), slog.String("status", "503"), false},
+		{"duration", Allow().AttrGT("latency", 500*time.Millisecond), slog.Duration("latency", time.Second), true},
+		{"duration against int", Allow().AttrGT("latency", 500), slog.Duration("latency", time.Second), false},
+		{"time before", Allow().AttrLT("at", at), slog.Time("at", at.Add(-time.Hour)), true},
+		{"time after", Allow().AttrLT("at", at), slog.Time("at", at.Add(time.Hour)), false},
+		{"string order", Allow().AttrGTE("name", "m"), slog.String("name", "zed"), true},
+		{"in ints", Allow().AttrIn("status", 502, 503, 504), slog.Int("status", 503), true},
+		{"not in ints", Allow().AttrIn("status", 502, 503, 504), slog.Int("status", 500), false},
+		{"in strings", Allow().AttrIn("env", "dev", "staging"), slog.String("env", "staging"), true},
+		{"in bools", Allow().AttrIn("cached", true), slog.Bool("cached", true), true},
+		{"in missing", Allow().AttrIn("env", "dev"), slog.String("other", "dev"), false},
+		{"exists", Allow().AttrExists("err"), slog.Any("err", nil), true},
+		{"exists in a group", Allow().AttrExists("http.status"), slog.Group("http", slog.Int("status", 200)), true},
+		{"exists missing", Allow().AttrExists("err"), slog.String("other", "x"), false},
+		{"missing", Allow().AttrMissing("err"), slog.String("other", "x"), true},
+		{"missing present", Allow().AttrMissing("err"), slog.String("err", "eof"), false},
+		{"range", Allow().AttrGTE("status", 400).AttrLT("status", 500), slog.Int("status", 404), true},
+		{"range outside", Allow().AttrGTE("status", 400).AttrLT("status", 500), slog.Int("status", 503), false},
+	}
+
+	f := &FilterHandler{}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			record := slog.NewRecord(time.Time{}, slog.LevelInfo, "msg", 0)
+			record.AddAttrs(tt.attr)
+			if got := f.matchesFilter(record, tt.filter); got != tt.want {
+				t.Fatalf("matchesFilter() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_FilterHandler_matchesFilter_Levels(t *testing.T) {
+	tests := []struct {
+		name   string
+		filter Filter
+		level  slog.Level
+		want   bool
+	}{
+		{"above excludes the level itself", Allow().Above(slog.LevelInfo), slog.LevelInfo, false},
+		{"above", Allow().Above(slog.LevelInfo), slog.LevelWarn, true},
+		{"at least includes the level itself", Allow().AtLeast(slog.LevelWarn), slog.LevelWarn, true},
+		{"at least", Allow().AtLeast(slog.LevelWarn), slog.LevelInfo, false},
+		{"between includes lo", Allow().Between(LevelTrace, slog.LevelDebug), LevelTrace, true},
+		{"between includes hi", Allow().Between(LevelTrace, slog.LevelDebug), slog.LevelDebug, true},
+		{"between excludes above hi", Allow().Between(LevelTrace, slog.LevelDebug), slog.LevelInfo, false},
+		{"exact level", Allow().Level(LevelFatal), LevelFatal, true},
+		{"exact level only", Allow().Level(LevelFatal), slog.LevelError, false},
+		{"criteria combine", Allow().Above(slog.LevelDebug).Below(slog.LevelWarn), slog.LevelInfo, true},
+		{"criteria combine (outside)", Allow().Above(slog.LevelDebug).Below(slog.LevelWarn), slog.LevelWarn, false},
+		{"disjoint criteria match nothing", Allow().Below(slog.LevelInfo).AtLeast(slog.LevelWarn), slog.LevelDebug, false},
+		{"below the lowest level matches nothing", Allow().Below(minLevel), minLevel, false},
+	}
+
+	f := &FilterHandler{}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := f.matchesFilter(newRecord(tt.level, "msg"), tt.filter); got != tt.want {
+				t.Fatalf("matchesFilter() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_FilterHandler_Enabled_LevelDeny(t *testing.T) {
+	tests := []struct {
+		name    string
+		filters []Filter
+		level   slog.Level
+		want    bool
+	}{
+		{"no filters", nil, slog.LevelDebug, true},
+		{"level floor", []Filter{Deny().Below(slog.LevelInfo)}, slog.LevelDebug, false},
+		{"level floor lets the floor through", []Filter{Deny().Below(slog.LevelInfo)}, slog.LevelInfo, true},
+		{"deny without criteria", []Filter{Deny()}, slog.LevelError, false},
+		{"deny on attrs is left to Handle", []Filter{Deny().Below(slog.LevelInfo).Attr("k", "v")}, slog.LevelDebug, true},
+		{"earlier allow may rescue", []Filter{Allow().Attr("component", "payments"), Deny().Below(slog.LevelInfo)}, slog.LevelDebug, true},
+		{"earlier allow at another level cannot", []Filter{Allow().AtLeast(slog.LevelWarn), Deny().Below(slog.LevelInfo)}, slog.LevelDebug, false},
+		{"later allow cannot", []Filter{Deny().Below(slog.LevelInfo), Allow()}, slog.LevelDebug, false},
+		{"shorten is ignored", []Filter{Shorten("body"), Deny().Level(LevelTrace)}, LevelTrace, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fl := NewFilterHandler(newRecHandler(LevelTrace), tt.filters...)
+			if got := fl.Enabled(context.Background(), tt.level); got != tt.want {
+				t.Fatalf("Enabled(%v) = %v, want %v", tt.level, got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_New_LevelDenyDisablesLevel(t *testing.T) {
+	logger := New(WithText(&bytes.Buffer{}), WithLevel(LevelTrace), WithFilters(Deny().Below(slog.LevelInfo)))
+
+	ctx := context.Background()
+	if logger.Slog().Enabled(ctx, slog.LevelDebug) {
+		t.Fatal("Enabled(Debug) = true under a Deny().Below(Info) filter, want false")
+	}
+	if logger.WithLevel(LevelTrace).Slog().Enabled(ctx, slog.LevelDebug) {
+		t.Fatal("a derived logger reports a level the filters deny as enabled")
+	}
+	if !logger.Slog().Enabled(ctx, slog.LevelInfo) {
+		t.Fatal("Enabled(Info) = false, want true")
+	}
+}
+
+func Test_FilterHandler_Handle_NoMatchDoesNotAllocate(t *testing.T) {
+	fl := NewFilterHandler(noopHandler{},
+		Deny().Attr("path", "/healthz*"),
+		Deny().Message("*timeout*"),
+		Deny().AttrGTE("status", 500).Attr("http.method", "POST"),
+		Deny().Where(Not(Match().AttrExists("user"))),
+	)
+	ctx := context.Background()
+	rec := newRecord(slog.LevelInfo, "request", "user", "bob", "path", "/api/users")
+	rec.AddAttrs(slog.Int("status", 200), slog.Group("http", slog.String("method", "GET")))
+
+	if allocs := testing.AllocsPerRun(100, func() { _ = fl.Handle(ctx, rec) }); allocs != 0 {
+		t.Fatalf("Handle() allocated %v times per record no filter acts on, want 0", allocs)
+	}
+}
+
+func Test_FilterHandler_Handle_WithAttrsNoMatchDoesNotAllocate(t *testing.T) {
+	fl := NewFilterHandler(noopHandler{},
+		Deny().Attr("request_id", "internal-*"),
+		Deny().Attr("http.path", "/healthz*"),
+		Shorten("http.body").Message("*dump*"),
+	).WithAttrs([]slog.Attr{slog.String("request_id", "r-1")}).WithGroup("http")
+	ctx := context.Background()
+	rec := newRecord(slog.LevelInfo, "request", "path", "/api/users", "body", "{}")
+
+	if allocs := testing.AllocsPerRun(100, func() { _ = fl.Handle(ctx, rec) }); allocs != 0 {
+		t.Fatalf("Handle() on a With-derived child allocated %v times per record no filter acts on, want 0", allocs)
+	}
+}
+
 func Test_Filter_Attr_DoesNotMutateSharedBase(t *testing.T) {
 	base := Allow().Attr("a", "1")
 	withB := base.Attr("b", "2")
@@ -197,6 +400,101 @@ func Test_FilterHandler_Handle_Allow(t *testing.T) {
 	}
 }
 
+func Test_FilterHandler_Handle_Ordering(t *testing.T) {
+	payments := []string{"component", "payments"}
+	tests := []struct {
+		name    string
+		filters []Filter
+		level   slog.Level
+		kv      []string
+		want    bool
+	}{
+		{
+			name:    "allow rescues a record from a later deny",
+			filters: []Filter{Allow().Attr("component", "payments"), Deny().Below(slog.LevelInfo)},
+			level:   slog.LevelDebug,
+			kv:      payments,
+			want:    true,
+		},
+		{
+			name:    "later deny still applies when the allow does not match",
+			filters: []Filter{Allow().Attr("component", "payments"), Deny().Below(slog.LevelInfo)},
+			level:   slog.LevelDebug,
+			kv:      []string{"component", "auth"},
+			want:    false,
+		},
+		{
+			name:    "earlier deny wins over a later allow",
+			filters: []Filter{Deny().Below(slog.LevelInfo), Allow().Attr("component", "payments")},
+			level:   slog.LevelDebug,
+			kv:      payments,
+			want:    false,
+		},
+		{
+			name:    "allow rescues from every later deny",
+			filters: []Filter{Allow().Attr("component", "payments"), Deny().Below(slog.LevelInfo), Deny().Attr("component", "pay*")},
+			level:   slog.LevelDebug,
+			kv:      payments,
+			want:    true,
+		},
+		{
+			name:    "shorten before deny does not rescue",
+			filters: []Filter{Shorten("component"), Deny().Below(slog.LevelInfo)},
+			level:   slog.LevelDebug,
+			kv:      payments,
+			want:    false,
+		},
+		{
+			name:    "bare Match allows everything",
+			filters: []Filter{Match(), Deny()},
+			level:   slog.LevelDebug,
+			want:    true,
+		},
+		{
+			name:    "non-matching deny before an allow is skipped",
+			filters: []Filter{Deny().Message("other"), Allow(), Deny()},
+			level:   slog.LevelInfo,
+			want:    true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			down := newRecHandler(LevelTrace)
+			fl := NewFilterHandler(down, tt.filters...)
+
+			if err := fl.Handle(context.Background(), newRecord(tt.level, "msg", tt.kv...)); err != nil {
+				t.Fatalf("Handle() returned error: %v", err)
+			}
+			if got := len(down.seen()) == 1; got != tt.want {
+				t.Fatalf("record kept = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_FilterHandler_Handle_AllowThenShorten(t *testing.T) {
+	down := newRecHandler(LevelTrace)
+	fl := NewFilterHandler(down,
+		Allow().Attr("component", "payments"),
+		Deny().Below(slog.LevelInfo),
+		Shorten("body").Limit(5),
+	)
+
+	rec := newRecord(slog.LevelDebug, "msg", "component", "payments", "body", "0123456789")
+	if err := fl.Handle(context.Background(), rec); err != nil {
+		t.Fatalf("Handle() returned error: %v", err)
+	}
+
+	seen := down.seen()
+	if len(seen) != 1 {
+		t.Fatalf("downstream saw %d records, want 1", len(seen))
+	}
+	if got := attrsOf(seen[0])["body"]; got != "01..." {
+		t.Fatalf("body = %q, want %q (rewrites still apply after an allow)", got, "01...")
+	}
+}
+
 func Test_FilterHandler_Handle_Shorten(t *testing.T) {
 	tests := []struct {
 		name    string
@@ -285,6 +583,9 @@ func Test_shortenMessage(t *testing.T) {
 		{"zero limit", "abcdef", 0, ""},
 		{"negative limit does not panic", "abcdef", -5, ""},
 		{"negative limit on empty string", "", -5, ""},
+		{"does not split a rune", "héllo wörld", 5, "h..."},
+		{"cut at a rune boundary", "日本語テキスト", 10, "日本..."},
+		{"tiny limit inside a rune", "日本", 2, ""},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -295,6 +596,40 @@ func Test_shortenMessage(t *testing.T) {
 	}
 }
 
+func Test_Filter_shorten_CountTruncated(t *testing.T) {
+	tests := []struct {
+		filter Filter
+		in     string
+		want   string
+	}{
+		{Shorten().Limit(10).CountTruncated(), "0123456789ABCDEF", "0123456... (+9 bytes)"},
+		{Shorten().Limit(10).CountTruncated(), "short", "short"},
+		{Shorten().Limit(2).CountTruncated(), "0123", "01 (+2 bytes)"},
+		{Shorten().Limit(5).CountTruncated(), "héllo wörld", "h... (+12 bytes)"},
+	}
+	for _, tt := range tests {
+		if got := tt.filter.shorten(tt.in); got != tt.want {
+			t.Errorf("shorten(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func Test_FilterHandler_Handle_ShortenGroupsAndMessage(t *testing.T) {
+	var out bytes.Buffer
+	fl := NewFilterHandler(slog.NewJSONHandler(&out, nil), Shorten("msg", "req", "http.body").Limit(5))
+
+	slog.New(fl).Info("a long message",
+		slog.Group("req", "path", "/very/long", "status", 200, slog.Group("inner", "q", "x=1&y=2")),
+		slog.Group("http", "body", "0123456789", "other", "0123456789"),
+	)
+
+	want := `"msg":"a ...","req":{"path":"/v...","status":200,"inner":{"q":"x=..."}},` +
+		`"http":{"body":"01...","other":"0123456789"}}`
+	if !strings.HasSuffix(strings.TrimSpace(out.String()), want) {
+		t.Fatalf("output = %s, want suffix %s", out.String(), want)
+	}
+}
+
 func Test_FilterHandler_Handle_ShortenNegativeLimit(t *testing.T) {
 	down := newRecHandler(LevelTrace)
 	fl := NewFilterHandler(down, Shorten("body").Limit(-5))
@@ -336,6 +671,72 @@ func Test_FilterHandler_ConcurrentWithAndAddFilter(t *testing.T) {
 	wg.Wait()
 }
 
+func Test_FilterHandler_UpdatesReachDerivedHandlers(t *testing.T) {
+	down := newRecHandler(LevelTrace)
+	root := NewFilterHandler(down)
+	child := root.WithAttrs([]slog.Attr{slog.String("component", "cache")}).WithGroup("g")
+
+	root.AddFilter(Deny().Attr("component", "cache"))
+	_ = child.Handle(context.Background(), newRecord(slog.LevelInfo, "added"))
+
+	// updates made through a derived handler reach the root too.
+	child.(*FilterHandler).SetFilters([]Filter{Deny().Message("set")})
+	_ = root.Handle(context.Background(), newRecord(slog.LevelInfo, "set"))
+	_ = child.Handle(context.Background(), newRecord(slog.LevelInfo, "kept"))
+
+	seen := down.seen()
+	if len(seen) != 1 || seen[0].Message != "kept" {
+		t.Fatalf("downstream saw %v, want only the kept record", seen)
+	}
+}
+
+func Test_Logger_FilterUpdatesRaceWithChildren(t *testing.T) {
+	var buf lockedBuffer
+	logger := New(WithText(&buf), WithFilters())
+	filters := filterHandlerOf(logger)
+
+	children := make([]Logger, 4)
+	for i := range children {
+		children[i] = logger.With("worker", i)
+	}
+
+	var wg sync.WaitGroup
+	stop := make(chan struct{})
+	for _, child := range children {
+		child := child
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-stop:
+					return
+				default:
+					child.Info("tick")
+				}
+			}
+		}()
+	}
+	for j := 0; j < 100; j++ {
+		filters.AddFilter(Deny().Message("never"))
+		filters.SetFilters(nil)
+	}
+	filters.SetFilters([]Filter{Deny().Attr("worker", "*")})
+	close(stop)
+	wg.Wait()
+
+	// once the deny is in, no child made before it gets a record through.
+	before := buf.String()
+	for _, child := range children {
+		child.Info("tick")
+	}
+	logger.With("other", 1).Info("tock")
+	after := strings.TrimPrefix(buf.String(), before)
+	if strings.Contains(after, "tick") || !strings.Contains(after, "tock") {
+		t.Fatalf("output after the update = %q, want only the tock record", after)
+	}
+}
+
 func Test_FilterHandler_AddAndSetFilters(t *testing.T) {
 	down := newRecHandler(LevelTrace)
 	fl := NewFilterHandler(down)
@@ -364,3 +765,253 @@ func Test_FilterHandler_AddAndSetFilters(t *testing.T) {
 		}
 	}
 }
+
+func Test_FilterHandler_SeesWithAttrsAndGroups(t *testing.T) {
+	tests := []struct {
+		name   string
+		filter Filter
+		log    func(l *slog.Logger)
+		denied bool
+	}{
+		{
+			name:   "with attr matches a wildcard",
+			filter: Deny().Attr("component", "cache*"),
+			log:    func(l *slog.Logger) { l.With("component", "cache-redis").Info("hit") },
+			denied: true,
+		},
+		{
+			name:   "with attr that does not match",
+			filter: Deny().Attr("component", "cache*"),
+			log:    func(l *slog.Logger) { l.With("component", "db").Info("hit") },
+			denied: false,
+		},
+		{
+			name:   "record attr under a group uses the qualified key",
+			filter: Deny().Attr("http.path", "/healthz"),
+			log:    func(l *slog.Logger) { l.WithGroup("http").Info("req", "path", "/healthz") },
+			denied: true,
+		},
+		{
+			name:   "unqualified key does not match inside a group",
+			filter: Deny().Attr("path", "/healthz"),
+			log:    func(l *slog.Logger) { l.WithGroup("http").Info("req", "path", "/healthz") },
+			denied: false,
+		},
+		{
+			name:   "with attr added inside a group is qualified",
+			filter: Deny().Attr("http.method", "GET"),
+			log:    func(l *slog.Logger) { l.WithGroup("http").With("method", "GET").Info("req") },
+			denied: true,
+		},
+		{
+			name:   "with attr added before a group stays unqualified",
+			filter: Deny().Attr("svc", "api"),
+			log:    func(l *slog.Logger) { l.With("svc", "api").WithGroup("http").Info("req") },
+			denied: true,
+		},
+		{
+			name:   "inline group value is flattened",
+			filter: Deny().Attr("http.path", "/metrics"),
+			log:    func(l *slog.Logger) { l.Info("req", slog.Group("http", "path", "/metrics")) },
+			denied: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			down := newRecHandler(LevelTrace)
+			tt.log(slog.New(NewFilterHandler(down, tt.filter)))
+
+			if got := len(down.seen()) == 0; got != tt.denied {
+				t.Fatalf("denied = %v, want %v", got, tt.denied)
+			}
+		})
+	}
+}
+
+func Test_FilterHandler_PreservesWithAttrsAndGroupsInOutput(t *testing.T) {
+	emit := func(h slog.Handler) {
+		l := slog.New(h).With("svc", "api").WithGroup("http").With("method", "GET").WithGroup("req")
+		l.Info("done", "status", 200)
+		l.WithGroup("empty").Info("no attrs")
+	}
+	// drop the timestamp so the two outputs compare byte for byte.
+	opts := &slog.HandlerOptions{ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
+		if len(groups) == 0 && a.Key == slog.TimeKey {
+			return slog.Attr{}
+		}
+		return a
+	}}
+	var want, got bytes.Buffer
+	emit(slog.NewJSONHandler(&want, opts))
+	emit(NewFilterHandler(slog.NewJSONHandler(&got, opts)))
+
+	if got.String() != want.String() {
+		t.Fatalf("output through FilterHandler differs:\n got: %s\nwant: %s", got.String(), want.String())
+	}
+}
+
+func Test_FilterHandler_Handle_ShortenWithAttrs(t *testing.T) {
+	var out bytes.Buffer
+	fl := NewFilterHandler(slog.NewJSONHandler(&out, nil), Shorten("http.body").Limit(5))
+
+	slog.New(fl).WithGroup("http").With("body", "0123456789").Info("resp")
+
+	var rec map[string]any
+	if err := json.Unmarshal(out.Bytes(), &rec); err != nil {
+		t.Fatalf("unmarshal %q: %v", out.String(), err)
+	}
+	http, _ := rec["http"].(map[string]any)
+	if http["body"] != "01..." {
+		t.Fatalf("http.body = %v, want %q", http["body"], "01...")
+	}
+}
+
+func Test_FilterHandler_matchesFilter_Composite(t *testing.T) {
+	authOrDebug := Deny().Below(slog.LevelInfo).Where(Not(Match().Attr("component", "auth")))
+	probes := Deny().Where(Any(Match().Attr("path", "/healthz"), Match().Attr("path", "/metrics")))
+
+	tests := []struct {
+		name   string
+		filter Filter
+		record slog.Record
+		want   bool
+	}{
+		{
+			name:   "not: debug from another component matches",
+			filter: authOrDebug,
+			record: newRecord(slog.LevelDebug, "m", "component", "db"),
+			want:   true,
+		},
+		{
+			name:   "not: debug from auth is excluded",
+			filter: authOrDebug,
+			record: newRecord(slog.LevelDebug, "m", "component", "auth"),
+			want:   false,
+		},
+		{
+			name:   "not: base criteria still apply (info is not below info)",
+			filter: authOrDebug,
+			record: newRecord(slog.LevelInfo, "m", "component", "db"),
+			want:   false,
+		},
+		{
+			name:   "not: a missing attribute counts as not matching",
+			filter: authOrDebug,
+			record: newRecord(slog.LevelDebug, "m"),
+			want:   true,
+		},
+		{
+			name:   "any: first alternative",
+			filter: probes,
+			record: newRecord(slog.LevelInfo, "m", "path", "/healthz"),
+			want:   true,
+		},
+		{
+			name:   "any: second alternative",
+			filter: probes,
+			record: newRecord(slog.LevelInfo, "m", "path", "/metrics"),
+			want:   true,
+		},
+		{
+			name:   "any: no alternative",
+			filter: probes,
+			record: newRecord(slog.LevelInfo, "m", "path", "/api"),
+			want:   false,
+		},
+		{
+			name:   "any with no criteria matches nothing",
+			filter: Deny().Where(Any()),
+			record: newRecord(slog.LevelInfo, "m"),
+			want:   false,
+		},
+		{
+			name:   "all with no criteria matches everything",
+			filter: Deny().Where(All()),
+			record: newRecord(slog.LevelInfo, "m"),
+			want:   true,
+		},
+		{
+			name: "all inside any: one branch fully matches",
+			filter: Deny().Where(Any(
+				All(Match().Attr("a", "1"), Match().Attr("b", "2")),
+				Match().Attr("c", "3"),
+			)),
+			record: newRecord(slog.LevelInfo, "m", "a", "1", "b", "2"),
+			want:   true,
+		},
+		{
+			name: "all inside any: a half-matching branch is not enough",
+			filter: Deny().Where(Any(
+				All(Match().Attr("a", "1"), Match().Attr("b", "2")),
+				Match().Attr("c", "3"),
+			)),
+			record: newRecord(slog.LevelInfo, "m", "a", "1", "b", "x"),
+			want:   false,
+		},
+		{
+			name:   "not of any: matches when no alternative does",
+			filter: Deny().Where(Not(Any(Match().Message("a"), Match().Message("b")))),
+			record: newRecord(slog.LevelInfo, "c"),
+			want:   true,
+		},
+		{
+			name:   "not of any: excluded when an alternative does",
+			filter: Deny().Where(Not(Any(Match().Message("a"), Match().Message("b")))),
+			record: newRecord(slog.LevelInfo, "b"),
+			want:   false,
+		},
+		{
+			name:   "double negation",
+			filter: Deny().Where(Not(Not(Match().Message("a")))),
+			record: newRecord(slog.LevelInfo, "a"),
+			want:   true,
+		},
+		{
+			name:   "several where calls are all required",
+			filter: Deny().Where(Match().Message("a")).Where(Match().Attr("k", "v")),
+			record: newRecord(slog.LevelInfo, "a", "k", "other"),
+			want:   false,
+		},
+		{
+			name:   "the action of nested criteria is ignored",
+			filter: Allow().Where(Deny().Message("a")),
+			record: newRecord(slog.LevelInfo, "a"),
+			want:   true,
+		},
+	}
+
+	f := &FilterHandler{}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := f.matchesFilter(tt.record, tt.filter); got != tt.want {
+				t.Fatalf("matchesFilter() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_FilterHandler_Handle_CompositeWithShorten(t *testing.T) {
+	down := newRecHandler(LevelTrace)
+	fl := NewFilterHandler(down, Shorten("body").Limit(5).Where(Not(Match().Attr("debug", "true"))))
+
+	_ = fl.Handle(context.Background(), newRecord(slog.LevelInfo, "a", "body", "0123456789"))
+	_ = fl.Handle(context.Background(), newRecord(slog.LevelInfo, "b", "body", "0123456789", "debug", "true"))
+
+	seen := down.seen()
+	if got := attrsOf(seen[0])["body"]; got != "01..." {
+		t.Fatalf("body = %q, want it shortened", got)
+	}
+	if got := attrsOf(seen[1])["body"]; got != "0123456789" {
+		t.Fatalf("body = %q, want it untouched when debug=true", got)
+	}
+}
+
+func Test_Filter_Where_DoesNotMutateSharedBase(t *testing.T) {
+	base := Deny().Where(Match().Message("a"))
+	_ = base.Where(Match().Message("b"))
+	_ = base.Where(Match().Message("c"))
+
+	if len(base.all) != 1 {
+		t.Fatalf("base filter gained criteria from a derived filter: %d", len(base.all))
+	}
+}
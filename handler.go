@@ -3,31 +3,136 @@ package log
 import (
 	"context"
 	"log/slog"
+	"runtime"
+	"time"
 )
 
 // logger wraps *slog.Logger to implement Logger, adding the Trace/Fatal helpers
-// and a per-logger WithLevel.
+// and a per-logger WithLevel. level is the Leveler SetLevel controls (nil for a
+// wrapped *slog.Logger), components the override tree shared by every logger
+// derived from the same root, filters the FilterHandler New installed (if any),
+// name the dotted component name set by Named, unnamed the same logger without
+// the "logger" attribute carrying that name, and skip the CallerSkip applied to
+// source locations.
 type logger struct {
-	slog *slog.Logger
+	slog       *slog.Logger
+	unnamed    *slog.Logger
+	level      slog.Leveler
+	components *Components
+	filters    *FilterHandler
+	name       string
+	skip       int
 }
 
 var _ Logger = (*logger)(nil)
 
 // With returns a logger that adds args to every subsequent record.
 func (l *logger) With(args ...any) Logger {
-	return &logger{slog: l.slog.With(args...)}
+	child := l.derive(l.slog.With(args...), l.level)
+	if l.unnamed != nil {
+		child.unnamed = l.unnamed.With(args...)
+	}
+	return child
+}
+
+// derive returns a logger around s and level that keeps everything else of l.
+func (l *logger) derive(s *slog.Logger, level slog.Leveler) *logger {
+	return &logger{slog: s, unnamed: l.unnamed, level: level, components: l.components, filters: l.filters, name: l.name, skip: l.skip}
+}
+
+// CallerSkip returns a logger that attributes records n frames further up the
+// stack, for thin wrappers around Logger.
+func (l *logger) CallerSkip(n int) Logger {
+	child := l.derive(l.slog, l.level)
+	child.skip += n
+	return child
 }
 
 // WithLevel returns a logger whose minimum level is level, preserving the
 // underlying handler's writer, format, and attributes. It wraps the existing
-// handler rather than rebuilding it, so a custom output is not lost.
+// handler rather than rebuilding it, so a custom output is not lost. The child
+// gets its own LevelVar, so SetLevel on it leaves the parent alone.
 func (l *logger) WithLevel(level slog.Level) Logger {
+	lv := new(slog.LevelVar)
+	lv.Set(level)
+	return l.WithLeveler(lv)
+}
+
+// WithLeveler is WithLevel with a threshold read from level on every record, so
+// a shared *slog.LevelVar can raise or lower it at runtime.
+func (l *logger) WithLeveler(level slog.Leveler) Logger {
 	base := l.slog.Handler()
+	lh := &levelHandler{level: level, Handler: base}
 	// unwrap a previous level wrapper so repeated calls don't nest.
-	if lh, ok := base.(*levelHandler); ok {
-		base = lh.Handler
+	if prev, ok := base.(*levelHandler); ok {
+		lh.Handler, lh.gate, lh.settings = prev.Handler, prev.gate, prev.settings
+	}
+	return l.derive(slog.New(lh), level)
+}
+
+// Named returns a logger for the component name, nested under this logger's
+// own name with a dot (Named("db").Named("pool") is "db.pool"). Its records
+// carry a "logger" attribute with the full name, and its level is the nearest
+// override in Components, else this logger's level, resolved on every record.
+// SetLevel on a named logger sets its override.
+func (l *logger) Named(name string) Logger {
+	full := qualify(l.name, name)
+	fallback := l.level
+	if cl, ok := fallback.(*componentLevel); ok {
+		fallback = cl.fallback
+	}
+	if fallback == nil {
+		fallback = handlerLevel{handler: l.slog.Handler()}
+	}
+	level := &componentLevel{components: l.components, name: full, fallback: fallback}
+
+	// name the unnamed logger, so a nested name replaces the outer one
+	// instead of adding a second "logger" attribute.
+	unnamed := l.unnamed
+	if unnamed == nil {
+		unnamed = l.slog
+	}
+	child := l.derive(unnamed.With("logger", full), l.level).WithLeveler(level).(*logger)
+	child.name, child.unnamed = full, unnamed
+	return child
+}
+
+// Components returns the override tree behind Named loggers, shared by every
+// logger derived from the same root.
+func (l *logger) Components() *Components {
+	return l.components
+}
+
+// Level reports the minimum level from the logger's Leveler. A wrapped
+// *slog.Logger has none, so its handler is probed for the lowest enabled
+// level of this package's ladder.
+func (l *logger) Level() slog.Level {
+	if l.level != nil {
+		return l.level.Level()
+	}
+	return probeLevel(l.slog.Handler())
+}
+
+// SetLevel sets the logger's level, or for a named logger its override;
+// see Logger.SetLevel for when that is a no-op.
+func (l *logger) SetLevel(level slog.Level) {
+	switch lv := l.level.(type) {
+	case *slog.LevelVar:
+		lv.Set(level)
+	case rootLevel:
+		lv.Set(level)
+	case *componentLevel:
+		lv.components.Set(lv.name, level)
+	}
+}
+
+// controlsLevel reports whether SetLevel actually moves the threshold.
+func (l *logger) controlsLevel() bool {
+	switch l.level.(type) {
+	case *slog.LevelVar, rootLevel, *componentLevel:
+		return true
 	}
-	return &logger{slog: slog.New(&levelHandler{level: level, Handler: base})}
+	return false
 }
 
 // Enabled reports whether the underlying handler emits records at level.
@@ -50,19 +155,106 @@ func (l *logger) WithGroup(name string) slog.Handler {
 	return l.slog.Handler().WithGroup(name)
 }
 
-func (l *logger) Error(msg string, args ...any) { l.slog.Error(msg, args...) }
-func (l *logger) Info(msg string, args ...any)  { l.slog.Info(msg, args...) }
-func (l *logger) Debug(msg string, args ...any) { l.slog.Debug(msg, args...) }
-func (l *logger) Warn(msg string, args ...any)  { l.slog.Warn(msg, args...) }
-
 // Trace logs at the custom LevelTrace.
 func (l *logger) Trace(msg string, args ...any) {
-	l.slog.Log(context.Background(), LevelTrace, msg, args...)
+	l.log(context.Background(), 0, LevelTrace, msg, args)
+}
+
+func (l *logger) Debug(msg string, args ...any) {
+	l.log(context.Background(), 0, slog.LevelDebug, msg, args)
+}
+
+func (l *logger) Info(msg string, args ...any) {
+	l.log(context.Background(), 0, slog.LevelInfo, msg, args)
+}
+
+func (l *logger) Warn(msg string, args ...any) {
+	l.log(context.Background(), 0, slog.LevelWarn, msg, args)
+}
+
+func (l *logger) Error(msg string, args ...any) {
+	l.log(context.Background(), 0, slog.LevelError, msg, args)
 }
 
 // Fatal logs at the custom LevelFatal. It does not exit the process.
 func (l *logger) Fatal(msg string, args ...any) {
-	l.slog.Log(context.Background(), LevelFatal, msg, args...)
+	l.log(context.Background(), 0, LevelFatal, msg, args)
+}
+
+// TraceContext logs at the custom LevelTrace, passing ctx to the handler.
+func (l *logger) TraceContext(ctx context.Context, msg string, args ...any) {
+	l.log(ctx, 0, LevelTrace, msg, args)
+}
+
+func (l *logger) DebugContext(ctx context.Context, msg string, args ...any) {
+	l.log(ctx, 0, slog.LevelDebug, msg, args)
+}
+
+func (l *logger) InfoContext(ctx context.Context, msg string, args ...any) {
+	l.log(ctx, 0, slog.LevelInfo, msg, args)
+}
+
+func (l *logger) WarnContext(ctx context.Context, msg string, args ...any) {
+	l.log(ctx, 0, slog.LevelWarn, msg, args)
+}
+
+func (l *logger) ErrorContext(ctx context.Context, msg string, args ...any) {
+	l.log(ctx, 0, slog.LevelError, msg, args)
+}
+
+// FatalContext logs at the custom LevelFatal, passing ctx to the handler. It
+// does not exit the process.
+func (l *logger) FatalContext(ctx context.Context, msg string, args ...any) {
+	l.log(ctx, 0, LevelFatal, msg, args)
+}
+
+// Log emits a record at level, passing ctx to the handler.
+func (l *logger) Log(ctx context.Context, level slog.Level, msg string, args ...any) {
+	l.log(ctx, 0, level, msg, args)
+}
+
+// LogAttrs emits a record at level with attrs, passing ctx to the handler.
+func (l *logger) LogAttrs(ctx context.Context, level slog.Level, msg string, attrs ...slog.Attr) {
+	l.logAttrs(ctx, 0, level, msg, attrs)
+}
+
+// log builds the record itself rather than going through *slog.Logger, so the
+// captured PC is the caller of the exported method (plus skip and CallerSkip
+// frames) instead of a frame inside this package.
+func (l *logger) log(ctx context.Context, skip int, level slog.Level, msg string, args []any) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	h := l.slog.Handler()
+	if !h.Enabled(ctx, level) {
+		return
+	}
+	r := slog.NewRecord(time.Now(), level, msg, callerPC(skip+l.skip))
+	r.Add(args...)
+	_ = h.Handle(ctx, r)
+}
+
+// logAttrs is log for callers that already hold attrs.
+func (l *logger) logAttrs(ctx context.Context, skip int, level slog.Level, msg string, attrs []slog.Attr) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	h := l.slog.Handler()
+	if !h.Enabled(ctx, level) {
+		return
+	}
+	r := slog.NewRecord(time.Now(), level, msg, callerPC(skip+l.skip))
+	r.AddAttrs(attrs...)
+	_ = h.Handle(ctx, r)
+}
+
+// callerPC returns the PC of the frame that called into this package, skip
+// frames further up. It skips runtime.Callers, callerPC itself, log/logAttrs,
+// and the exported method.
+func callerPC(skip int) uintptr {
+	var pcs [1]uintptr
+	runtime.Callers(4+skip, pcs[:])
+	return pcs[0]
 }
 
 // Slog returns the wrapped *slog.Logger.
@@ -82,24 +274,128 @@ func (h discardHandler) WithGroup(string) slog.Handler           { return h }
 // levelHandler wraps a slog.Handler to enforce a minimum level while delegating
 // everything else, so WithLevel can change the threshold without touching the
 // output destination or format.
+//
+// For a logger built by New with outputs added by WithOutput, gate holds
+// those outputs, which keep their own levels: a record below the threshold
+// still goes down the chain when one of them is enabled for it, marked with
+// the gate so that the logger's gated Text and JSON outputs skip it. settings, when set, is the logger's settings:
+// Handle reads them once and checks the record against that version's level
+// and filters, so a record sees one config or the other, never a mix.
 type levelHandler struct {
 	level slog.Leveler
 	slog.Handler
+	gate     *levelGate
+	settings *settingsVar
 }
 
 var _ slog.Handler = (*levelHandler)(nil)
 
-// Enabled reports whether level meets the wrapper's minimum.
-func (h *levelHandler) Enabled(_ context.Context, level slog.Level) bool {
-	return level >= h.level.Level()
+// Enabled reports whether level meets the wrapper's minimum, or an ungated
+// output is enabled for it. The wrapped handler's own Enabled is not
+// consulted, so the threshold can be lowered past it, except that a level it
+// would drop outright in Handle anyway (see levelDenier) is reported disabled.
+func (h *levelHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	if level < h.level.Level() && (h.gate == nil || !h.gate.ungated.Enabled(ctx, level)) {
+		return false
+	}
+	if d, ok := h.Handler.(levelDenier); ok {
+		return !d.deniesLevel(level)
+	}
+	return true
+}
+
+// Handle forwards the record, marking it for the gated outputs to skip when
+// it is below the threshold. With settings the threshold is checked again
+// against the version the filters will use, as a reload may have moved it
+// since Enabled.
+func (h *levelHandler) Handle(ctx context.Context, record slog.Record) error {
+	if h.settings == nil && h.gate == nil {
+		return h.Handler.Handle(ctx, record)
+	}
+	var s *settings
+	if h.settings != nil {
+		s = h.settings.Load()
+	}
+	if record.Level < h.threshold(s) {
+		if h.gate == nil {
+			return nil
+		}
+		ctx = context.WithValue(ctx, belowLevelKey{}, h.gate)
+	}
+	if f, ok := h.Handler.(*FilterHandler); ok && s != nil && f.settings == h.settings {
+		return f.handle(ctx, record, s.plan)
+	}
+	return h.Handler.Handle(ctx, record)
+}
+
+// threshold returns the minimum level, taken from s when the Leveler keeps it
+// in settings.
+func (h *levelHandler) threshold(s *settings) slog.Level {
+	if lv, ok := h.level.(settingsLeveler); ok && s != nil {
+		return lv.levelIn(s)
+	}
+	return h.level.Level()
 }
 
 // WithAttrs wraps the child handler's result, preserving the level threshold.
 func (h *levelHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
-	return &levelHandler{level: h.level, Handler: h.Handler.WithAttrs(attrs)}
+	return &levelHandler{level: h.level, Handler: h.Handler.WithAttrs(attrs), gate: h.gate, settings: h.settings}
 }
 
 // WithGroup wraps the child handler's result, preserving the level threshold.
 func (h *levelHandler) WithGroup(name string) slog.Handler {
-	return &levelHandler{level: h.level, Handler: h.Handler.WithGroup(name)}
+	return &levelHandler{level: h.level, Handler: h.Handler.WithGroup(name), gate: h.gate, settings: h.settings}
+}
+
+// belowLevelKey marks the context of a record below its logger's level that
+// levelHandler lets through for the ungated outputs. Its value is the
+// logger's *levelGate, so the gated outputs of another logger further down
+// the chain, such as one added with WithOutput, are not affected.
+type belowLevelKey struct{}
+
+// levelGate is the level gate of a logger built by New, shared by its
+// levelHandlers and its gated outputs. ungated holds the outputs the gate
+// does not apply to.
+type levelGate struct {
+	ungated slog.Handler
+}
+
+// gatedHandler is a Text or JSON output of a logger built by New, which skips
+// records below the logger's level (see levelHandler).
+type gatedHandler struct {
+	slog.Handler
+	gate *levelGate
+}
+
+var _ slog.Handler = gatedHandler{}
+
+// below reports whether ctx marks the record as below this output's gate.
+func (h gatedHandler) below(ctx context.Context) bool {
+	gate, _ := ctx.Value(belowLevelKey{}).(*levelGate)
+	return gate == h.gate
+}
+
+// Enabled reports false for records marked below the logger's level, and asks
+// the output otherwise.
+func (h gatedHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return !h.below(ctx) && h.Handler.Enabled(ctx, level)
+}
+
+// Handle skips records marked below the logger's level, for callers that do
+// not ask Enabled first.
+func (h gatedHandler) Handle(ctx context.Context, record slog.Record) error {
+	if h.below(ctx) {
+		return nil
+	}
+	return h.Handler.Handle(ctx, record)
+}
+
+// WithAttrs wraps the output's result.
+func (h gatedHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return gatedHandler{Handler: h.Handler.WithAttrs(attrs), gate: h.gate}
+}
+
+// WithGroup wraps the output's result.
+func (h gatedHandler) WithGroup(name string) slog.Handler {
+	return gatedHandler{Handler: h.Handler.WithGroup(name), gate: h.gate}
 }
@@ -52,6 +52,89 @@ func Test_Logger_WithLevel_CanWidenAgain(t *testing.T) {
 	}
 }
 
+func Test_Logger_SetLevel_ControlsNewLevelVar(t *testing.T) {
+	var buf bytes.Buffer
+	logger := New(WithText(&buf), WithLevel(slog.LevelInfo))
+	child := logger.With("component", "db")
+
+	child.Debug("hidden")
+	logger.SetLevel(slog.LevelDebug)
+	child.Debug("shown")
+
+	if got := child.Level(); got != slog.LevelDebug {
+		t.Fatalf("child Level() = %v, want %v (With children share the level)", got, slog.LevelDebug)
+	}
+	out := buf.String()
+	if strings.Contains(out, "hidden") {
+		t.Fatalf("debug was logged before SetLevel lowered the threshold: %q", out)
+	}
+	if !strings.Contains(out, "shown") {
+		t.Fatalf("SetLevel on the root did not reach the With child: %q", out)
+	}
+}
+
+func Test_Logger_WithLevel_ChildLevelIsIndependent(t *testing.T) {
+	var buf bytes.Buffer
+	logger := New(WithText(&buf), WithLevel(LevelTrace))
+	child := logger.WithLevel(slog.LevelError)
+
+	child.SetLevel(slog.LevelWarn)
+
+	if got := logger.Level(); got != LevelTrace {
+		t.Fatalf("parent Level() = %v, want %v (child SetLevel leaked)", got, LevelTrace)
+	}
+	if got := child.Level(); got != slog.LevelWarn {
+		t.Fatalf("child Level() = %v, want %v", got, slog.LevelWarn)
+	}
+
+	child.Info("hidden")
+	child.Warn("shown")
+	out := buf.String()
+	if strings.Contains(out, "hidden") || !strings.Contains(out, "shown") {
+		t.Fatalf("child did not log at its runtime level: %q", out)
+	}
+}
+
+func Test_Logger_WithLeveler_FollowsSharedLevelVar(t *testing.T) {
+	var buf bytes.Buffer
+	logger := New(WithText(&buf), WithLevel(LevelTrace))
+
+	lv := new(slog.LevelVar)
+	lv.Set(slog.LevelError)
+	a := logger.WithLeveler(lv)
+	b := logger.WithLeveler(lv).With("k", "v")
+
+	a.Info("hidden")
+	lv.Set(slog.LevelInfo)
+	a.Info("shown-a")
+	b.SetLevel(slog.LevelWarn) // b shares lv, so a follows too
+	a.Info("hidden-again")
+
+	out := buf.String()
+	if strings.Contains(out, "hidden") {
+		t.Fatalf("record logged below the shared level: %q", out)
+	}
+	if !strings.Contains(out, "shown-a") {
+		t.Fatalf("lowering the LevelVar did not take effect: %q", out)
+	}
+	if got := a.Level(); got != slog.LevelWarn {
+		t.Fatalf("Level() = %v, want %v", got, slog.LevelWarn)
+	}
+}
+
+func Test_Logger_Level_ProbesWrappedHandler(t *testing.T) {
+	logger := Wrap(slog.New(newRecHandler(slog.LevelWarn)))
+	if got := logger.Level(); got != slog.LevelWarn {
+		t.Fatalf("Level() = %v, want %v", got, slog.LevelWarn)
+	}
+
+	// the wrapped handler owns its level, so SetLevel leaves it alone.
+	logger.SetLevel(slog.LevelDebug)
+	if got := logger.Level(); got != slog.LevelWarn {
+		t.Fatalf("Level() after SetLevel = %v, want %v", got, slog.LevelWarn)
+	}
+}
+
 func Test_Logger_With_AddsAttributes(t *testing.T) {
 	var buf bytes.Buffer
 	base := slog.New(slog.NewJSONHandler(&buf, HandlerOptions(LevelTrace)))
@@ -97,6 +180,110 @@ func Test_Logger_CustomLevels(t *testing.T) {
 	}
 }
 
+func Test_Logger_ContextMethods_ThreadCtxThroughHandlers(t *testing.T) {
+	tests := []struct {
+		name      string
+		log       func(l Logger, ctx context.Context)
+		wantLevel slog.Level
+	}{
+		{"trace", func(l Logger, ctx context.Context) { l.TraceContext(ctx, "m") }, LevelTrace},
+		{"debug", func(l Logger, ctx context.Context) { l.DebugContext(ctx, "m") }, slog.LevelDebug},
+		{"info", func(l Logger, ctx context.Context) { l.InfoContext(ctx, "m") }, slog.LevelInfo},
+		{"warn", func(l Logger, ctx context.Context) { l.WarnContext(ctx, "m") }, slog.LevelWarn},
+		{"error", func(l Logger, ctx context.Context) { l.ErrorContext(ctx, "m") }, slog.LevelError},
+		{"fatal", func(l Logger, ctx context.Context) { l.FatalContext(ctx, "m") }, LevelFatal},
+		{"log", func(l Logger, ctx context.Context) { l.Log(ctx, slog.LevelWarn, "m", "k", "v") }, slog.LevelWarn},
+		{"log attrs", func(l Logger, ctx context.Context) {
+			l.LogAttrs(ctx, slog.LevelInfo, "m", slog.String("k", "v"))
+		}, slog.LevelInfo},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			down := newCtxHandler()
+			// filter -> fan-out -> sink, so ctx must survive both wrappers.
+			h := NewFilterHandler(NewMultiHandler(down), Deny().Message("never"))
+			logger := Wrap(slog.New(h))
+
+			ctx := context.WithValue(context.Background(), ctxKey{}, "req-1")
+			tt.log(logger, ctx)
+
+			values, levels := down.seen()
+			if len(values) != 1 {
+				t.Fatalf("downstream saw %d records, want 1", len(values))
+			}
+			if values[0] != "req-1" {
+				t.Fatalf("ctx value = %v, want %q (ctx was not threaded through)", values[0], "req-1")
+			}
+			if levels[0] != tt.wantLevel {
+				t.Fatalf("level = %v, want %v", levels[0], tt.wantLevel)
+			}
+		})
+	}
+}
+
+func Test_Logger_ReportsCallerSource(t *testing.T) {
+	tests := []struct {
+		name string
+		log  func(l Logger) (string, int)
+	}{
+		{"info", func(l Logger) (string, int) {
+			l.Info("m")
+			return here(-1)
+		}},
+		{"trace", func(l Logger) (string, int) {
+			l.Trace("m")
+			return here(-1)
+		}},
+		{"fatal context", func(l Logger) (string, int) {
+			l.FatalContext(context.Background(), "m")
+			return here(-1)
+		}},
+		{"log attrs", func(l Logger) (string, int) {
+			l.LogAttrs(context.Background(), slog.LevelInfo, "m")
+			return here(-1)
+		}},
+		{"with", func(l Logger) (string, int) {
+			l.With("k", "v").Warn("m")
+			return here(-1)
+		}},
+		{"with level", func(l Logger) (string, int) {
+			l.WithLevel(slog.LevelDebug).Error("m")
+			return here(-1)
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			logger := New(WithJSON(&buf), WithLevel(LevelTrace), WithSource())
+
+			wantFile, wantLine := tt.log(logger)
+
+			file, line := sourceOf(t, &buf)
+			if file != wantFile || line != wantLine {
+				t.Fatalf("source = %s:%d, want %s:%d", file, line, wantFile, wantLine)
+			}
+		})
+	}
+}
+
+// infof is a thin wrapper of the kind CallerSkip exists for.
+func infof(l Logger, msg string) {
+	l.CallerSkip(1).Info(msg)
+}
+
+func Test_Logger_CallerSkip_AttributesWrapperCaller(t *testing.T) {
+	var buf bytes.Buffer
+	logger := New(WithJSON(&buf), WithSource())
+
+	infof(logger, "m")
+	wantFile, wantLine := here(-1)
+
+	file, line := sourceOf(t, &buf)
+	if file != wantFile || line != wantLine {
+		t.Fatalf("source = %s:%d, want %s:%d (wrapper frame was not skipped)", file, line, wantFile, wantLine)
+	}
+}
+
 func Test_levelHandler_Enabled(t *testing.T) {
 	down := newRecHandler(LevelTrace)
 	lh := &levelHandler{level: slog.LevelWarn, Handler: down}
@@ -0,0 +1,33 @@
+package log
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+)
+
+// Hash starts a filter that replaces the values of the given attribute keys on
+// matching records with a stable pseudonym: the first 16 hex characters of the
+// HMAC-SHA256 of the value under secret. The same value always maps to the
+// same pseudonym, so records stay correlatable without exposing it. Change the
+// length with Limit (at most 64).
+//
+//	log.Hash(secret, "user_id", "http.client_ip")
+//
+// The secret is required: an unkeyed hash of a value with few possibilities,
+// such as an IP address, can be reversed by hashing them all. With an empty
+// secret the values are masked with "[REDACTED]" instead.
+func Hash(secret []byte, keys ...string) Filter {
+	return Filter{action: hash, keys: keys, limit: 16, hashKey: append([]byte(nil), secret...)}
+}
+
+// pseudonym returns the hex HMAC of value under f's key, cut to f's limit.
+func (f Filter) pseudonym(value string) string {
+	mac := hmac.New(sha256.New, f.hashKey)
+	_, _ = mac.Write([]byte(value))
+	sum := hex.EncodeToString(mac.Sum(nil))
+	if f.limit > 0 && f.limit < len(sum) {
+		return sum[:f.limit]
+	}
+	return sum
+}
@@ -0,0 +1,76 @@
+package log
+
+import (
+	"bytes"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func Test_Filter_pseudonym(t *testing.T) {
+	f := Hash([]byte("k1"))
+	a, b := f.pseudonym("u-42"), f.pseudonym("u-42")
+	if a != b {
+		t.Fatalf("pseudonym is not stable: %q != %q", a, b)
+	}
+	if len(a) != 16 {
+		t.Fatalf("len(pseudonym) = %d, want 16", len(a))
+	}
+	if other := f.pseudonym("u-43"); other == a {
+		t.Fatalf("different values share pseudonym %q", a)
+	}
+	if rekeyed := Hash([]byte("k2")).pseudonym("u-42"); rekeyed == a {
+		t.Fatalf("different keys share pseudonym %q", a)
+	}
+	if full := f.Limit(100).pseudonym("u-42"); len(full) != 64 || full[:16] != a {
+		t.Fatalf("pseudonym with limit 100 = %q, want the full 64-character HMAC", full)
+	}
+}
+
+func Test_FilterHandler_Handle_Hash(t *testing.T) {
+	secret := []byte("s3cr3t")
+	var buf bytes.Buffer
+	h := NewFilterHandler(slog.NewJSONHandler(&buf, nil), Hash(secret, "user_id", "http.client_ip").Message("request"))
+	logger := slog.New(h).With("user_id", "u-42").WithGroup("http")
+
+	logger.Info("request", "client_ip", "10.0.0.7", "path", "/")
+	logger.Info("other", "client_ip", "10.0.0.7")
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	f := Hash(secret)
+	for _, want := range []string{
+		`"user_id":"` + f.pseudonym("u-42") + `"`,
+		`"http":{"client_ip":"` + f.pseudonym("10.0.0.7") + `","path":"/"}`,
+	} {
+		if !strings.Contains(lines[0], want) {
+			t.Fatalf("output %s lacks %s", lines[0], want)
+		}
+	}
+	if !strings.Contains(lines[1], `"client_ip":"10.0.0.7"`) {
+		t.Fatalf("unmatched record rewritten: %s", lines[1])
+	}
+}
+
+func Test_FilterHandler_Handle_Hash_InlineGroup(t *testing.T) {
+	var buf bytes.Buffer
+	secret := []byte("s3cr3t")
+	logger := slog.New(NewFilterHandler(slog.NewJSONHandler(&buf, nil), Hash(secret, "http.client_ip")))
+
+	logger.Info("request", slog.Group("http", "client_ip", "10.0.0.1", "path", "/"))
+
+	want := `"http":{"client_ip":"` + Hash(secret).pseudonym("10.0.0.1") + `","path":"/"}`
+	if out := buf.String(); !strings.Contains(out, want) || strings.Contains(out, "10.0.0.1") {
+		t.Fatalf("output %s, want %s", out, want)
+	}
+}
+
+func Test_FilterHandler_Handle_Hash_WithoutSecret(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(NewFilterHandler(slog.NewJSONHandler(&buf, nil), Hash(nil, "ip")))
+
+	logger.Info("request", "ip", "10.0.0.1")
+
+	if out := buf.String(); !strings.Contains(out, `"ip":"[REDACTED]"`) {
+		t.Fatalf("output %s, want the value masked rather than hashed without a secret", out)
+	}
+}
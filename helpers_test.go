@@ -1,9 +1,16 @@
 package log
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
 	"log/slog"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
 	"sync"
+	"testing"
 	"time"
 )
 
@@ -96,6 +103,42 @@ func (h *fakeHandler) messages() []string {
 	return append([]string{}, *h.handled...)
 }
 
+// ctxKey is a private context key for asserting ctx reaches a handler.
+type ctxKey struct{}
+
+// ctxHandler records the ctxKey value and level of every record it handles, so
+// tests can check that a caller's ctx is threaded all the way down.
+type ctxHandler struct {
+	mu     *sync.Mutex
+	values *[]any
+	levels *[]slog.Level
+}
+
+var _ slog.Handler = (*ctxHandler)(nil)
+
+func newCtxHandler() *ctxHandler {
+	return &ctxHandler{mu: &sync.Mutex{}, values: &[]any{}, levels: &[]slog.Level{}}
+}
+
+func (h *ctxHandler) Enabled(context.Context, slog.Level) bool { return true }
+
+func (h *ctxHandler) Handle(ctx context.Context, r slog.Record) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	*h.values = append(*h.values, ctx.Value(ctxKey{}))
+	*h.levels = append(*h.levels, r.Level)
+	return nil
+}
+
+func (h *ctxHandler) WithAttrs([]slog.Attr) slog.Handler { return h }
+func (h *ctxHandler) WithGroup(string) slog.Handler      { return h }
+
+func (h *ctxHandler) seen() ([]any, []slog.Level) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return append([]any{}, *h.values...), append([]slog.Level{}, *h.levels...)
+}
+
 // noopHandler discards everything and allocates nothing, so benchmarks measure
 // the handler under test rather than the sink.
 type noopHandler struct{}
@@ -126,3 +169,78 @@ func newRecord(level slog.Level, msg string, kv ...string) slog.Record {
 	}
 	return r
 }
+
+// lockedBuffer is a bytes.Buffer safe to write from a background goroutine
+// while a test reads it.
+type lockedBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *lockedBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *lockedBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+// eventually polls cond until it holds or a second has passed.
+func eventually(t *testing.T, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatal("condition not met within 1s")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+// here returns the file and line of its caller, offset by delta lines, so a
+// test can name the line its log call sits on.
+func here(delta int) (string, int) {
+	_, file, line, _ := runtime.Caller(1)
+	return filepath.Base(file), line + delta
+}
+
+// sourceOf decodes the single JSON record in buf and returns its source
+// file (base name) and line, as emitted with AddSource.
+func sourceOf(t *testing.T, buf *bytes.Buffer) (string, int) {
+	t.Helper()
+	var rec struct {
+		Source struct {
+			File string `json:"file"`
+			Line int    `json:"line"`
+		} `json:"source"`
+	}
+	if err := json.Unmarshal([]byte(strings.TrimSpace(buf.String())), &rec); err != nil {
+		t.Fatalf("unmarshal %q: %v", buf.String(), err)
+	}
+	return filepath.Base(rec.Source.File), rec.Source.Line
+}
+
+// readFile returns the contents of path, failing the test on error.
+func readFile(t *testing.T, path string) string {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("read %s: %v", path, err)
+	}
+	return string(data)
+}
+
+// matchesFilter reports whether record satisfies every set criterion of filter.
+// Attribute criteria see the held WithAttrs attributes as well as the record's,
+// with group-qualified keys. Handle uses its precompiled plan instead; this
+// compiles one for filter alone, so tests can check criteria one at a time.
+func (f *FilterHandler) matchesFilter(record slog.Record, filter Filter) bool {
+	plan := newFilterPlan([]Filter{filter})
+	values := make([]attrSlot, len(plan.slots))
+	plan.collect(f, record, nil, nil, values)
+	return plan.matches(record, filter, values)
+}
@@ -6,10 +6,13 @@
 package log
 
 import (
+	"context"
 	"io"
 	"log/slog"
+	"math"
 	"os"
 	"sync"
+	"time"
 )
 
 // Logger is the extended slog contract: a slog.Handler plus level-aware helpers
@@ -20,6 +23,26 @@ type Logger interface {
 	// WithLevel returns a logger with a new minimum level, preserving the
 	// underlying outputs, format, and attributes.
 	WithLevel(level slog.Level) Logger
+	// WithLeveler is WithLevel backed by a slog.Leveler (typically a
+	// *slog.LevelVar), so the child's threshold can change at runtime.
+	WithLeveler(level slog.Leveler) Logger
+	// Level reports the logger's current minimum level.
+	Level() slog.Level
+	// SetLevel changes the minimum level at runtime for this logger and every
+	// logger sharing its level: those derived via With, and for a logger built
+	// by New, its Text and JSON outputs. On a Named logger it sets the
+	// component's override. It has no effect on loggers adopted by Wrap, whose
+	// handler owns its level, or on a WithLeveler logger whose Leveler is not a
+	// *slog.LevelVar.
+	SetLevel(level slog.Level)
+	// Named returns a logger for a dotted component name whose level follows
+	// the overrides in Components, falling back to this logger's level.
+	Named(name string) Logger
+	// Components returns the per-component level overrides used by Named.
+	Components() *Components
+	// CallerSkip returns a logger that reports source locations n frames
+	// further up the stack, for thin wrappers that log on behalf of a caller.
+	CallerSkip(n int) Logger
 	Trace(msg string, args ...any)
 	Debug(msg string, args ...any)
 	Info(msg string, args ...any)
@@ -27,6 +50,17 @@ type Logger interface {
 	Error(msg string, args ...any)
 	// Fatal logs at LevelFatal and returns; it does not exit the process.
 	Fatal(msg string, args ...any)
+	TraceContext(ctx context.Context, msg string, args ...any)
+	DebugContext(ctx context.Context, msg string, args ...any)
+	InfoContext(ctx context.Context, msg string, args ...any)
+	WarnContext(ctx context.Context, msg string, args ...any)
+	ErrorContext(ctx context.Context, msg string, args ...any)
+	// FatalContext logs at LevelFatal with ctx; it does not exit the process.
+	FatalContext(ctx context.Context, msg string, args ...any)
+	// Log emits a record at an arbitrary level, passing ctx to the handler.
+	Log(ctx context.Context, level slog.Level, msg string, args ...any)
+	// LogAttrs is the more efficient Log for callers that already hold attrs.
+	LogAttrs(ctx context.Context, level slog.Level, msg string, attrs ...slog.Attr)
 	// Slog returns the wrapped *slog.Logger as an escape hatch.
 	Slog() *slog.Logger
 }
@@ -39,6 +73,13 @@ const (
 	LevelFatal = slog.Level(12)
 )
 
+// minLevel and maxLevel bound every level: a threshold at minLevel lets
+// everything through, one at maxLevel nothing.
+const (
+	minLevel = slog.Level(math.MinInt)
+	maxLevel = slog.Level(math.MaxInt)
+)
+
 var levelNames = map[slog.Leveler]string{
 	LevelTrace: "TRACE",
 	LevelFatal: "FATAL",
@@ -70,17 +111,32 @@ func HandlerOptions(level slog.Leveler) *slog.HandlerOptions {
 type Option func(*builder)
 
 type builder struct {
-	level   *slog.LevelVar
-	outputs []func(*slog.LevelVar) slog.Handler
-	filters []Filter
+	settings   *settingsVar
+	level      rootLevel
+	components *Components
+	source     bool
+	outputs    []output
+	filtered   bool
+	filters    []Filter
+	sampling   *SamplingOptions
+	// errs are configuration problems options could not act on; New reports
+	// them through the finished logger.
+	errs []error
+}
+
+// output is an output of a logger built by New. gated outputs, the Text and
+// JSON ones, follow the logger's level; the others keep their own.
+type output struct {
+	build func(*slog.HandlerOptions) slog.Handler
+	gated bool
 }
 
 // WithText adds a text handler writing to w.
 func WithText(w io.Writer) Option {
 	return func(b *builder) {
-		b.outputs = append(b.outputs, func(lv *slog.LevelVar) slog.Handler {
-			return slog.NewTextHandler(w, HandlerOptions(lv))
-		})
+		b.outputs = append(b.outputs, output{gated: true, build: func(opts *slog.HandlerOptions) slog.Handler {
+			return slog.NewTextHandler(w, opts)
+		}})
 	}
 }
 
@@ -88,9 +144,9 @@ func WithText(w io.Writer) Option {
 // lumberjack.Logger) here to keep that dependency out of this module.
 func WithJSON(w io.Writer) Option {
 	return func(b *builder) {
-		b.outputs = append(b.outputs, func(lv *slog.LevelVar) slog.Handler {
-			return slog.NewJSONHandler(w, HandlerOptions(lv))
-		})
+		b.outputs = append(b.outputs, output{gated: true, build: func(opts *slog.HandlerOptions) slog.Handler {
+			return slog.NewJSONHandler(w, opts)
+		}})
 	}
 }
 
@@ -98,7 +154,7 @@ func WithJSON(w io.Writer) Option {
 // The handler controls its own level; WithLevel does not affect it.
 func WithOutput(h slog.Handler) Option {
 	return func(b *builder) {
-		b.outputs = append(b.outputs, func(*slog.LevelVar) slog.Handler { return h })
+		b.outputs = append(b.outputs, output{build: func(*slog.HandlerOptions) slog.Handler { return h }})
 	}
 }
 
@@ -107,47 +163,93 @@ func WithLevel(level slog.Level) Option {
 	return func(b *builder) { b.level.Set(level) }
 }
 
-// WithFilters wraps the assembled outputs in a FilterHandler.
+// WithSource makes the Text and JSON outputs report the caller's file and line.
+func WithSource() Option {
+	return func(b *builder) { b.source = true }
+}
+
+// WithFilters wraps the assembled outputs in a FilterHandler, even with no
+// filters, so filters can be added at runtime.
 func WithFilters(filters ...Filter) Option {
-	return func(b *builder) { b.filters = append(b.filters, filters...) }
+	return func(b *builder) {
+		b.filtered = true
+		b.filters = append(b.filters, filters...)
+	}
+}
+
+// WithSampling samples the records the filters keep, across every output, as
+// NewSamplingHandler does. A later WithSampling replaces an earlier one.
+func WithSampling(opts SamplingOptions) Option {
+	return func(b *builder) { b.sampling = &opts }
 }
 
-// New assembles a Logger from the given outputs, level, and filters. With no
+// New assembles a Logger from the given outputs, level, filters, and sampling. With no
 // outputs it writes text to stdout at Debug.
 func New(opts ...Option) Logger {
-	b := &builder{level: new(slog.LevelVar)}
-	b.level.Set(slog.LevelDebug)
+	settings := newSettingsVar()
+	b := &builder{settings: settings, level: rootLevel{settings}, components: &Components{settings: settings}}
 	for _, opt := range opts {
 		opt(b)
 	}
 
 	if len(b.outputs) == 0 {
-		b.outputs = append(b.outputs, func(lv *slog.LevelVar) slog.Handler {
-			return slog.NewTextHandler(os.Stdout, HandlerOptions(lv))
-		})
+		WithText(os.Stdout)(b)
 	}
 
+	// the Text and JSON outputs pass every level; the builder's level is
+	// enforced by one gate over the whole chain instead, so WithLevel and
+	// Named children can be more verbose than the root. The gate lets records
+	// below it through to the other outputs, which keep their own levels.
 	handlers := make([]slog.Handler, len(b.outputs))
-	for i, build := range b.outputs {
-		handlers[i] = build(b.level)
+	gate := new(levelGate)
+	var ungated []slog.Handler
+	for i, out := range b.outputs {
+		opts := HandlerOptions(minLevel)
+		opts.AddSource = b.source
+		handlers[i] = out.build(opts)
+		if out.gated {
+			handlers[i] = gatedHandler{Handler: handlers[i], gate: gate}
+		} else {
+			ungated = append(ungated, handlers[i])
+		}
 	}
 
-	var h slog.Handler
-	if len(handlers) == 1 {
-		h = handlers[0]
-	} else {
-		h = NewMultiHandler(handlers...)
+	// always fan out, even to one output: MultiHandler checks each output's
+	// own level, which the gate above it does not.
+	outputs := NewMultiHandler(handlers...)
+	var h slog.Handler = outputs
+	if b.sampling != nil {
+		h = NewSamplingHandler(h, *b.sampling)
 	}
-	if len(b.filters) > 0 {
-		h = NewFilterHandler(h, b.filters...)
+	var filters *FilterHandler
+	if b.filtered {
+		filters = newFilterHandler(h, b.settings, b.filters)
+		h = filters
 	}
 
-	return Wrap(slog.New(h))
+	root := &levelHandler{level: b.level, Handler: h, settings: b.settings}
+	if len(ungated) > 0 {
+		gate.ungated = NewMultiHandler(ungated...)
+		root.gate = gate
+	}
+	l := &logger{
+		slog:       slog.New(root),
+		level:      b.level,
+		components: b.components,
+		filters:    filters,
+	}
+	// straight to the outputs, so neither the level nor a filter hides it.
+	for _, err := range b.errs {
+		r := slog.NewRecord(time.Now(), slog.LevelWarn, "invalid logging configuration", 0)
+		r.AddAttrs(slog.Any("err", err))
+		_ = outputs.Handle(context.Background(), r)
+	}
+	return l
 }
 
 // Wrap adopts an existing *slog.Logger as a Logger.
 func Wrap(l *slog.Logger) Logger {
-	return &logger{slog: l}
+	return &logger{slog: l, components: NewComponents()}
 }
 
 // Discard returns a Logger that drops every record. Useful as a default in
@@ -164,7 +266,11 @@ var (
 
 func init() {
 	globalLevel.Set(slog.LevelDebug)
-	defaultLog = Wrap(slog.New(slog.NewTextHandler(os.Stdout, HandlerOptions(globalLevel))))
+	defaultLog = &logger{
+		slog:       slog.New(slog.NewTextHandler(os.Stdout, HandlerOptions(globalLevel))),
+		level:      globalLevel,
+		components: NewComponents(),
+	}
 }
 
 // Default returns the process-wide Logger backing the package-level helpers.
@@ -174,38 +280,120 @@ func Default() Logger {
 	return defaultLog
 }
 
-// SetDefault replaces the process-wide Logger.
+// SetDefault replaces the process-wide Logger. A logger built by New keeps its
+// own level, which SetLevel then controls. Any other Logger (e.g. one adopted by
+// Wrap) is gated by a fresh level starting at its current Level, so SetLevel
+// works on it too; Default then returns the gated logger, not l itself.
 func SetDefault(l Logger) {
+	if !controlsLevel(l) {
+		lv := new(slog.LevelVar)
+		lv.Set(l.Level())
+		l = l.WithLeveler(lv)
+	}
 	mu.Lock()
 	defer mu.Unlock()
 	defaultLog = l
 }
 
-// SetLevel sets the minimum level of the default logger built in init. It has
-// no effect after SetDefault replaces the default with a logger of your own.
+// controlsLevel reports whether SetLevel on l actually moves its threshold.
+func controlsLevel(l Logger) bool {
+	lg, ok := l.(*logger)
+	return ok && lg.controlsLevel()
+}
+
+// SetLevel sets the minimum level of the current default logger, whether it is
+// the one built in init or one installed with SetDefault.
 func SetLevel(level slog.Level) {
-	globalLevel.Set(level)
+	Default().SetLevel(level)
 }
 
-// Level returns the default logger's minimum level.
+// Level returns the current default logger's minimum level.
 func Level() slog.Level {
-	return globalLevel.Level()
+	return Default().Level()
 }
 
 // Trace logs at LevelTrace on the default logger.
-func Trace(msg string, args ...any) { Default().Trace(msg, args...) }
+func Trace(msg string, args ...any) {
+	logDefault(context.Background(), LevelTrace, msg, args)
+}
 
 // Debug logs at slog.LevelDebug on the default logger.
-func Debug(msg string, args ...any) { Default().Debug(msg, args...) }
+func Debug(msg string, args ...any) {
+	logDefault(context.Background(), slog.LevelDebug, msg, args)
+}
 
 // Info logs at slog.LevelInfo on the default logger.
-func Info(msg string, args ...any) { Default().Info(msg, args...) }
+func Info(msg string, args ...any) {
+	logDefault(context.Background(), slog.LevelInfo, msg, args)
+}
 
 // Warn logs at slog.LevelWarn on the default logger.
-func Warn(msg string, args ...any) { Default().Warn(msg, args...) }
+func Warn(msg string, args ...any) {
+	logDefault(context.Background(), slog.LevelWarn, msg, args)
+}
 
 // Error logs at slog.LevelError on the default logger.
-func Error(msg string, args ...any) { Default().Error(msg, args...) }
+func Error(msg string, args ...any) {
+	logDefault(context.Background(), slog.LevelError, msg, args)
+}
 
 // Fatal logs at LevelFatal on the default logger; it does not exit the process.
-func Fatal(msg string, args ...any) { Default().Fatal(msg, args...) }
+func Fatal(msg string, args ...any) {
+	logDefault(context.Background(), LevelFatal, msg, args)
+}
+
+// TraceContext logs at LevelTrace with ctx on the default logger.
+func TraceContext(ctx context.Context, msg string, args ...any) {
+	logDefault(ctx, LevelTrace, msg, args)
+}
+
+// DebugContext logs at slog.LevelDebug with ctx on the default logger.
+func DebugContext(ctx context.Context, msg string, args ...any) {
+	logDefault(ctx, slog.LevelDebug, msg, args)
+}
+
+// InfoContext logs at slog.LevelInfo with ctx on the default logger.
+func InfoContext(ctx context.Context, msg string, args ...any) {
+	logDefault(ctx, slog.LevelInfo, msg, args)
+}
+
+// WarnContext logs at slog.LevelWarn with ctx on the default logger.
+func WarnContext(ctx context.Context, msg string, args ...any) {
+	logDefault(ctx, slog.LevelWarn, msg, args)
+}
+
+// ErrorContext logs at slog.LevelError with ctx on the default logger.
+func ErrorContext(ctx context.Context, msg string, args ...any) {
+	logDefault(ctx, slog.LevelError, msg, args)
+}
+
+// FatalContext logs at LevelFatal with ctx on the default logger; it does not
+// exit the process.
+func FatalContext(ctx context.Context, msg string, args ...any) {
+	logDefault(ctx, LevelFatal, msg, args)
+}
+
+// Log emits a record at level with ctx on the default logger.
+func Log(ctx context.Context, level slog.Level, msg string, args ...any) {
+	logDefault(ctx, level, msg, args)
+}
+
+// LogAttrs emits a record at level with ctx and attrs on the default logger.
+func LogAttrs(ctx context.Context, level slog.Level, msg string, attrs ...slog.Attr) {
+	if l, ok := Default().(*logger); ok {
+		l.logAttrs(ctx, 0, level, msg, attrs)
+		return
+	}
+	Default().LogAttrs(ctx, level, msg, attrs...)
+}
+
+// logDefault logs through the default logger on behalf of a package helper. A
+// logger of this package is called one frame deeper so the record's source is
+// the helper's caller; any other Logger goes through its own Log.
+func logDefault(ctx context.Context, level slog.Level, msg string, args []any) {
+	if l, ok := Default().(*logger); ok {
+		l.log(ctx, 1, level, msg, args)
+		return
+	}
+	Default().Log(ctx, level, msg, args...)
+}
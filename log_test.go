@@ -2,10 +2,12 @@ package log
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"log/slog"
 	"strings"
 	"testing"
+	"time"
 )
 
 // withGlobalLogger swaps the default logger for the duration of a test and
@@ -52,6 +54,82 @@ func Test_PackageHelpers_RouteToDefaultLogger(t *testing.T) {
 	}
 }
 
+func Test_PackageHelpers_ContextVariantsPassCtx(t *testing.T) {
+	tests := []struct {
+		name      string
+		log       func(ctx context.Context)
+		wantLevel slog.Level
+	}{
+		{"trace", func(ctx context.Context) { TraceContext(ctx, "m") }, LevelTrace},
+		{"debug", func(ctx context.Context) { DebugContext(ctx, "m") }, slog.LevelDebug},
+		{"info", func(ctx context.Context) { InfoContext(ctx, "m") }, slog.LevelInfo},
+		{"warn", func(ctx context.Context) { WarnContext(ctx, "m") }, slog.LevelWarn},
+		{"error", func(ctx context.Context) { ErrorContext(ctx, "m") }, slog.LevelError},
+		{"fatal", func(ctx context.Context) { FatalContext(ctx, "m") }, LevelFatal},
+		{"log", func(ctx context.Context) { Log(ctx, slog.LevelInfo, "m") }, slog.LevelInfo},
+		{"log attrs", func(ctx context.Context) { LogAttrs(ctx, slog.LevelError, "m") }, slog.LevelError},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			down := newCtxHandler()
+			withGlobalLogger(t, down)
+
+			tt.log(context.WithValue(context.Background(), ctxKey{}, "req-1"))
+
+			values, levels := down.seen()
+			if len(values) != 1 || values[0] != "req-1" {
+				t.Fatalf("ctx values = %v, want [req-1]", values)
+			}
+			if levels[0] != tt.wantLevel {
+				t.Fatalf("level = %v, want %v", levels[0], tt.wantLevel)
+			}
+		})
+	}
+}
+
+func Test_PackageHelpers_ReportCallerSource(t *testing.T) {
+	tests := []struct {
+		name string
+		log  func() (string, int)
+	}{
+		{"info", func() (string, int) {
+			Info("m")
+			return here(-1)
+		}},
+		{"fatal", func() (string, int) {
+			Fatal("m")
+			return here(-1)
+		}},
+		{"warn context", func() (string, int) {
+			WarnContext(context.Background(), "m")
+			return here(-1)
+		}},
+		{"log", func() (string, int) {
+			Log(context.Background(), slog.LevelInfo, "m")
+			return here(-1)
+		}},
+		{"log attrs", func() (string, int) {
+			LogAttrs(context.Background(), slog.LevelInfo, "m")
+			return here(-1)
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			prev := Default()
+			SetDefault(New(WithJSON(&buf), WithSource()))
+			t.Cleanup(func() { SetDefault(prev) })
+
+			wantFile, wantLine := tt.log()
+
+			file, line := sourceOf(t, &buf)
+			if file != wantFile || line != wantLine {
+				t.Fatalf("source = %s:%d, want %s:%d", file, line, wantFile, wantLine)
+			}
+		})
+	}
+}
+
 func Test_SetLevel_GatesPackageHelpers(t *testing.T) {
 	var buf bytes.Buffer
 	// the default logger built in init is wired to globalLevel, so a handler
@@ -74,6 +152,49 @@ func Test_SetLevel_GatesPackageHelpers(t *testing.T) {
 	}
 }
 
+func Test_SetLevel_ControlsLoggerInstalledWithSetDefault(t *testing.T) {
+	tests := []struct {
+		name  string
+		build func(w *bytes.Buffer) Logger
+	}{
+		{"built by New", func(w *bytes.Buffer) Logger {
+			return New(WithText(w), WithLevel(slog.LevelInfo))
+		}},
+		{"adopted by Wrap", func(w *bytes.Buffer) Logger {
+			return Wrap(slog.New(slog.NewTextHandler(w, HandlerOptions(slog.LevelInfo))))
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			prev := Default()
+			SetDefault(tt.build(&buf))
+			t.Cleanup(func() { SetDefault(prev) })
+
+			if got := Level(); got != slog.LevelInfo {
+				t.Fatalf("Level() = %v, want %v (should reflect the new default)", got, slog.LevelInfo)
+			}
+
+			Debug("hidden")
+			SetLevel(slog.LevelDebug)
+			Debug("shown")
+			SetLevel(slog.LevelError)
+			Warn("hidden too")
+
+			if got := Level(); got != slog.LevelError {
+				t.Fatalf("Level() = %v, want %v", got, slog.LevelError)
+			}
+			out := buf.String()
+			if strings.Contains(out, "hidden") {
+				t.Fatalf("record logged below the level set with SetLevel: %q", out)
+			}
+			if !strings.Contains(out, "shown") {
+				t.Fatalf("SetLevel did not lower the installed default's level: %q", out)
+			}
+		})
+	}
+}
+
 func Test_renameLevels_RendersCustomLevelNames(t *testing.T) {
 	tests := []struct {
 		level slog.Level
@@ -138,3 +259,65 @@ func Test_New_AssemblesOutputsAndFilters(t *testing.T) {
 		t.Fatalf("attr k = %v, want %q", rec["k"], "v")
 	}
 }
+
+func Test_New_WithOutputIgnoresWithLevel(t *testing.T) {
+	var buf bytes.Buffer
+	down := newRecHandler(LevelTrace)
+	logger := New(WithText(&buf), WithOutput(down), WithLevel(slog.LevelInfo))
+
+	logger.Trace("trace")
+	logger.Named("db").Debug("named debug")
+	logger.Info("info")
+
+	if seen := down.seen(); len(seen) != 3 {
+		t.Fatalf("output saw %d records, want all 3 at its own TRACE level", len(seen))
+	}
+	if out := buf.String(); strings.Contains(out, "trace") || strings.Contains(out, "named debug") || !strings.Contains(out, "msg=info") {
+		t.Fatalf("text output = %q, want only the info record", out)
+	}
+}
+
+func Test_New_WithOutputKeepsItsOwnLevel(t *testing.T) {
+	down := newRecHandler(slog.LevelWarn)
+	logger := New(WithOutput(down), WithLevel(LevelTrace))
+
+	logger.Info("below the output's level")
+	logger.WithLevel(LevelTrace).Debug("still below it")
+	logger.Warn("kept")
+
+	seen := down.seen()
+	if len(seen) != 1 || seen[0].Message != "kept" {
+		t.Fatalf("output saw %d records, want only the warn record", len(seen))
+	}
+}
+
+func Test_New_WithOutputLoggerKeepsItsOwnLevel(t *testing.T) {
+	var inner, outer bytes.Buffer
+	nested := New(WithText(&inner), WithLevel(slog.LevelDebug))
+	logger := New(WithLevel(slog.LevelWarn), WithOutput(nested), WithText(&outer))
+
+	logger.Info("hello")
+
+	if !strings.Contains(inner.String(), "msg=hello") {
+		t.Fatalf("nested logger output = %q, want the info record at its own DEBUG level", inner.String())
+	}
+	if outer.Len() != 0 {
+		t.Fatalf("text output = %q, want nothing below WARN", outer.String())
+	}
+}
+
+func Test_GatedHandler_SkipsMarkedRecordsWithoutEnabled(t *testing.T) {
+	var buf bytes.Buffer
+	gate := new(levelGate)
+	h := gatedHandler{Handler: slog.NewTextHandler(&buf, HandlerOptions(minLevel)), gate: gate}
+	r := slog.NewRecord(time.Now(), slog.LevelInfo, "below", 0)
+
+	_ = h.Handle(context.WithValue(context.Background(), belowLevelKey{}, gate), r)
+	if buf.Len() != 0 {
+		t.Fatalf("output = %q, want a record below its own gate skipped", buf.String())
+	}
+	_ = h.Handle(context.WithValue(context.Background(), belowLevelKey{}, new(levelGate)), r)
+	if !strings.Contains(buf.String(), "msg=below") {
+		t.Fatalf("output = %q, want a record below another logger's gate written", buf.String())
+	}
+}
@@ -0,0 +1,162 @@
+package log
+
+import (
+	"cmp"
+	"log/slog"
+	"strings"
+)
+
+// glob is a compiled pattern in which "*" matches any run of characters,
+// including none and including "/". Every other character is literal. A
+// pattern without "*" is an exact match.
+type glob struct {
+	raw string
+	// parts are raw split around "*": the first must prefix the value, the
+	// last must suffix it, and the ones between must appear in order.
+	parts []string
+}
+
+// compileGlob compiles pattern once, so matching only does string searches.
+func compileGlob(pattern string) glob {
+	return glob{raw: pattern, parts: strings.Split(pattern, "*")}
+}
+
+// wildcard reports whether g contains a "*".
+func (g glob) wildcard() bool { return len(g.parts) > 1 }
+
+// match reports whether s matches g.
+func (g glob) match(s string) bool {
+	if !g.wildcard() {
+		return s == g.raw
+	}
+	first, last := g.parts[0], g.parts[len(g.parts)-1]
+	if len(s) < len(first)+len(last) || !strings.HasPrefix(s, first) || !strings.HasSuffix(s, last) {
+		return false
+	}
+	s = s[len(first) : len(s)-len(last)]
+	// leftmost matching is enough: with only "*", taking each middle part as
+	// early as possible leaves the most room for the rest.
+	for _, part := range g.parts[1 : len(g.parts)-1] {
+		i := strings.Index(s, part)
+		if i < 0 {
+			return false
+		}
+		s = s[i+len(part):]
+	}
+	return true
+}
+
+// predicateOp is the comparison an attrPredicate makes.
+type predicateOp int
+
+const (
+	opGT predicateOp = iota
+	opGTE
+	opLT
+	opLTE
+	opIn
+	opExists
+	opMissing
+)
+
+// attrPredicate is a typed test of one attribute, built by Filter.AttrGT and
+// friends.
+type attrPredicate struct {
+	key    string
+	op     predicateOp
+	values []slog.Value
+}
+
+// match reports whether the attribute's value v satisfies p; present is
+// whether the record has the attribute at all.
+func (p attrPredicate) match(v slog.Value, present bool) bool {
+	switch p.op {
+	case opExists:
+		return present
+	case opMissing:
+		return !present
+	case opIn:
+		if !present {
+			return false
+		}
+		for _, want := range p.values {
+			if equalValues(v, want) {
+				return true
+			}
+		}
+		return false
+	default:
+		// an ordered comparison, below.
+	}
+
+	if !present {
+		return false
+	}
+	c, ok := compareValues(v, p.values[0])
+	if !ok {
+		return false
+	}
+	switch p.op {
+	case opGT:
+		return c > 0
+	case opGTE:
+		return c >= 0
+	case opLT:
+		return c < 0
+	default:
+		return c <= 0
+	}
+}
+
+// compareValues orders a against b by kind: numbers numerically, whatever mix
+// of int, uint and float they are; durations, times and strings within their
+// own kind. ok is false when the two cannot be ordered.
+func compareValues(a, b slog.Value) (c int, ok bool) {
+	if a.Kind() != b.Kind() {
+		if x, ok := numeric(a); ok {
+			if y, ok := numeric(b); ok {
+				return cmp.Compare(x, y), true
+			}
+		}
+		return 0, false
+	}
+	switch a.Kind() {
+	case slog.KindInt64:
+		return cmp.Compare(a.Int64(), b.Int64()), true
+	case slog.KindUint64:
+		return cmp.Compare(a.Uint64(), b.Uint64()), true
+	case slog.KindFloat64:
+		return cmp.Compare(a.Float64(), b.Float64()), true
+	case slog.KindDuration:
+		return cmp.Compare(a.Duration(), b.Duration()), true
+	case slog.KindTime:
+		return a.Time().Compare(b.Time()), true
+	case slog.KindString:
+		return strings.Compare(a.String(), b.String()), true
+	default:
+		return 0, false
+	}
+}
+
+// equalValues reports whether a equals b, comparing as compareValues does,
+// plus booleans.
+func equalValues(a, b slog.Value) bool {
+	if c, ok := compareValues(a, b); ok {
+		return c == 0
+	}
+	return a.Kind() == slog.KindBool && b.Kind() == slog.KindBool && a.Bool() == b.Bool()
+}
+
+// numeric returns v as a float64 if it is a number of any kind.
+func numeric(v slog.Value) (float64, bool) {
+	switch v.Kind() {
+	case slog.KindInt64:
+		return float64(v.Int64()), true
+	case slog.KindUint64:
+		return float64(v.Uint64()), true
+	case slog.KindFloat64:
+		return v.Float64(), true
+	default:
+		return 0, false
+	}
+}
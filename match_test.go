@@ -0,0 +1,37 @@
+package log
+
+import "testing"
+
+func Test_glob_match(t *testing.T) {
+	tests := []struct {
+		pattern string
+		value   string
+		want    bool
+	}{
+		{"exact", "exact", true},
+		{"exact", "exactly", false},
+		{"", "", true},
+		{"*", "", true},
+		{"*", "anything/at/all", true},
+		{"/healthz*", "/healthz/live", true},
+		{"/healthz*", "/health", false},
+		{"*failed", "request failed", true},
+		{"*failed", "failed request", false},
+		{"*timeout*", "i/o timeout", true},
+		{"*timeout*", "timeouts", true},
+		{"*timeout*", "time out", false},
+		{"/api/*/health", "/api/v1/health", true},
+		{"/api/*/health", "/api/v1/users/health", true},
+		{"/api/*/health", "/api/health", false},
+		{"a*b*c", "abc", true},
+		{"a*b*c", "aXbYbZc", true},
+		{"a*b*c", "acb", false},
+		{"ab*ba", "aba", false},
+		{"**", "x", true},
+	}
+	for _, tt := range tests {
+		if got := compileGlob(tt.pattern).match(tt.value); got != tt.want {
+			t.Errorf("glob %q match(%q) = %v, want %v", tt.pattern, tt.value, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,140 @@
+package log
+
+import (
+	"context"
+	"log/slog"
+	"strings"
+)
+
+// Components is a tree of per-component level overrides keyed by dotted names,
+// like log4j categories: an override on "db" applies to "db.pool" and
+// "db.pool.conn" unless a deeper name has its own. Loggers returned by
+// Logger.Named resolve their level from it on every record, so changes take
+// effect on loggers that already exist. Safe for concurrent use.
+type Components struct {
+	// settings holds the overrides; for a logger built by New it is shared
+	// with the filters and the root level, so a Reloader swaps them together.
+	settings *settingsVar
+}
+
+// NewComponents returns an empty override tree.
+func NewComponents() *Components {
+	return &Components{settings: newSettingsVar()}
+}
+
+// Set overrides the level of the named component and its descendants.
+func (c *Components) Set(name string, level slog.Level) {
+	c.settings.update(func(s *settings) {
+		s.overrides = withEntry(s.overrides, name, level)
+	})
+}
+
+// Unset removes the override on name, so it falls back to its nearest
+// ancestor's override or to the root logger's level.
+func (c *Components) Unset(name string) {
+	c.settings.update(func(s *settings) {
+		next := copyLevels(s.overrides)
+		delete(next, name)
+		s.overrides = next
+	})
+}
+
+// Replace swaps every override for levels in one step.
+func (c *Components) Replace(levels map[string]slog.Level) {
+	next := copyLevels(levels)
+	c.settings.update(func(s *settings) { s.overrides = next })
+}
+
+// Levels returns a copy of the current overrides.
+func (c *Components) Levels() map[string]slog.Level {
+	return copyLevels(c.settings.Load().overrides)
+}
+
+// Resolve returns the override that applies to name: its own, else that of
+// its nearest dotted ancestor. ok is false when no override applies.
+func (c *Components) Resolve(name string) (level slog.Level, ok bool) {
+	return resolve(c.settings.Load().overrides, name)
+}
+
+// resolve is Resolve over one version of the overrides.
+func resolve(overrides map[string]slog.Level, name string) (level slog.Level, ok bool) {
+	for {
+		if level, ok = overrides[name]; ok {
+			return level, true
+		}
+		i := strings.LastIndexByte(name, '.')
+		if i < 0 {
+			return 0, false
+		}
+		name = name[:i]
+	}
+}
+
+// copyLevels returns a copy of levels, never nil.
+func copyLevels(levels map[string]slog.Level) map[string]slog.Level {
+	out := make(map[string]slog.Level, len(levels))
+	for name, level := range levels {
+		out[name] = level
+	}
+	return out
+}
+
+// componentLevel is the Leveler behind a named logger: the nearest override in
+// the tree, else the level of the logger Named was first called on.
+type componentLevel struct {
+	components *Components
+	name       string
+	fallback   slog.Leveler
+}
+
+var _ slog.Leveler = (*componentLevel)(nil)
+
+// Level resolves the override for the component, falling back to the root.
+func (c *componentLevel) Level() slog.Level {
+	return c.levelIn(c.components.settings.Load())
+}
+
+// levelIn is Level with the overrides, and a root level read from settings,
+// taken from s.
+func (c *componentLevel) levelIn(s *settings) slog.Level {
+	if level, ok := resolve(s.overrides, c.name); ok {
+		return level
+	}
+	if fallback, ok := c.fallback.(settingsLeveler); ok {
+		return fallback.levelIn(s)
+	}
+	return c.fallback.Level()
+}
+
+// handlerLevel is a Leveler that probes a handler for the lowest level it has
+// enabled, for loggers whose level lives inside their handler.
+type handlerLevel struct {
+	handler slog.Handler
+}
+
+var _ slog.Leveler = handlerLevel{}
+
+// Level returns the lowest level of this package's ladder the handler enables.
+func (h handlerLevel) Level() slog.Level {
+	return probeLevel(h.handler)
+}
+
+// levelLadder is every level this package names, lowest first.
+var levelLadder = []slog.Level{LevelTrace, slog.LevelDebug, slog.LevelInfo, slog.LevelWarn, slog.LevelError, LevelFatal}
+
+// probeLevel returns the lowest level of levelLadder that h enables, or the
+// maximum level when it enables none.
+func probeLevel(h slog.Handler) slog.Level {
+	for _, level := range levelLadder {
+		if h.Enabled(context.Background(), level) {
+			return level
+		}
+	}
+	return maxLevel
+}
+
+// Named returns a logger for a component of the default logger; see
+// Logger.Named.
+func Named(name string) Logger {
+	return Default().Named(name)
+}
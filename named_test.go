@@ -0,0 +1,134 @@
+package log
+
+import (
+	"bytes"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func Test_Components_Resolve(t *testing.T) {
+	c := NewComponents()
+	c.Set("db", slog.LevelWarn)
+	c.Set("db.pool", LevelTrace)
+
+	tests := []struct {
+		name      string
+		wantLevel slog.Level
+		wantOK    bool
+	}{
+		{"db", slog.LevelWarn, true},
+		{"db.pool", LevelTrace, true},
+		{"db.pool.conn", LevelTrace, true},
+		{"db.query", slog.LevelWarn, true},
+		{"dbx", 0, false},
+		{"http", 0, false},
+		{"", 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			level, ok := c.Resolve(tt.name)
+			if ok != tt.wantOK || level != tt.wantLevel {
+				t.Fatalf("Resolve(%q) = %v, %v; want %v, %v", tt.name, level, ok, tt.wantLevel, tt.wantOK)
+			}
+		})
+	}
+
+	c.Unset("db.pool")
+	if level, _ := c.Resolve("db.pool"); level != slog.LevelWarn {
+		t.Fatalf("after Unset, Resolve(db.pool) = %v, want the db override %v", level, slog.LevelWarn)
+	}
+}
+
+func Test_Logger_Named_FollowsOverridesAtRuntime(t *testing.T) {
+	var buf bytes.Buffer
+	root := New(WithText(&buf), WithJSON(&bytes.Buffer{}), WithLevel(slog.LevelInfo))
+	pool := root.Named("db").Named("pool")
+	query := root.Named("db.query")
+
+	pool.Debug("pool-debug-1") // no override: root level Info applies
+	root.Components().Set("db", slog.LevelWarn)
+	root.Components().Set("db.pool", LevelTrace)
+	pool.Trace("pool-trace") // more verbose than the root, across both outputs
+	query.Info("query-info") // db=warn hides it
+	query.Warn("query-warn") // shown
+	root.Debug("root-debug") // root still at Info
+	root.Components().Unset("db.pool")
+	pool.Debug("pool-debug-2") // falls back to db=warn
+
+	out := buf.String()
+	for _, hidden := range []string{"pool-debug-1", "query-info", "root-debug", "pool-debug-2"} {
+		if strings.Contains(out, hidden) {
+			t.Fatalf("%s was logged despite the overrides: %q", hidden, out)
+		}
+	}
+	for _, shown := range []string{"pool-trace", "query-warn"} {
+		if !strings.Contains(out, shown) {
+			t.Fatalf("%s missing from output: %q", shown, out)
+		}
+	}
+	if !strings.Contains(out, "logger=db.pool") {
+		t.Fatalf("named record does not carry its logger name: %q", out)
+	}
+}
+
+func Test_Logger_Named_FallsBackToRootLevel(t *testing.T) {
+	var buf bytes.Buffer
+	root := New(WithText(&buf), WithLevel(slog.LevelWarn))
+	named := root.Named("http")
+
+	named.Info("hidden")
+	root.SetLevel(slog.LevelInfo)
+	named.Info("shown")
+
+	out := buf.String()
+	if strings.Contains(out, "hidden") || !strings.Contains(out, "shown") {
+		t.Fatalf("named logger without an override did not follow the root level: %q", out)
+	}
+}
+
+func Test_Logger_Named_SetLevelSetsOverride(t *testing.T) {
+	root := New(WithText(&bytes.Buffer{}), WithLevel(slog.LevelInfo))
+	named := root.Named("cache")
+
+	named.SetLevel(slog.LevelError)
+
+	if got := named.Level(); got != slog.LevelError {
+		t.Fatalf("Level() = %v, want %v", got, slog.LevelError)
+	}
+	if got := root.Components().Levels()["cache"]; got != slog.LevelError {
+		t.Fatalf("override for cache = %v, want %v", got, slog.LevelError)
+	}
+	if got := root.Level(); got != slog.LevelInfo {
+		t.Fatalf("root Level() = %v, want %v (named SetLevel leaked)", got, slog.LevelInfo)
+	}
+}
+
+func Test_Named_UsesDefaultLogger(t *testing.T) {
+	var buf bytes.Buffer
+	prev := Default()
+	SetDefault(New(WithText(&buf), WithLevel(slog.LevelInfo)))
+	t.Cleanup(func() { SetDefault(prev) })
+
+	worker := Named("worker")
+	worker.Debug("hidden")
+	Default().Components().Set("worker", slog.LevelDebug)
+	worker.Debug("shown")
+
+	out := buf.String()
+	if strings.Contains(out, "hidden") || !strings.Contains(out, "shown") {
+		t.Fatalf("package Named did not follow the default's overrides: %q", out)
+	}
+}
+
+func Test_Logger_Named_NestedNameReplacesOuter(t *testing.T) {
+	var buf bytes.Buffer
+	root := New(WithText(&buf))
+
+	root.Named("db").With("shard", 2).Named("pool").Info("nested")
+
+	out := buf.String()
+	if strings.Count(out, "logger=") != 1 || !strings.Contains(out, "logger=db.pool") || !strings.Contains(out, "shard=2") {
+		t.Fatalf("output = %q, want one logger=db.pool and the shard", out)
+	}
+}
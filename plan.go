@@ -0,0 +1,271 @@
+package log
+
+import "log/slog"
+
+// filterPlan is a filter list compiled for Handle: the attribute keys any
+// filter reads get a slot each, so one pass over the held and record attrs
+// collects every value the filters need, without building a map per record.
+// Plans are immutable once built.
+type filterPlan struct {
+	filters []Filter
+	// slots maps each qualified attribute key read by a filter, nested
+	// criteria included, to its index in the collected values.
+	slots map[string]int
+	// groups holds every group path that leads to a key in slots ("" and
+	// "http" for "http.path"), so the scan skips unrelated groups.
+	groups map[string]struct{}
+	// rewriters holds, per filter, the attr rewrite of a Shorten, Redact,
+	// Hash, Rename or DropAttrs filter, or nil. A rewrite returns the zero
+	// Attr to drop the attr.
+	rewriters []func(prefix string, attr slog.Attr) slog.Attr
+}
+
+// attrSlot is the value collected for one key of a plan.
+type attrSlot struct {
+	value   slog.Value
+	present bool
+}
+
+// maxStackSlots is how many slots Handle collects without allocating.
+const maxStackSlots = 16
+
+// newFilterPlan compiles filters. The plan keeps filters as given; callers must
+// not modify the slice afterwards.
+func newFilterPlan(filters []Filter) *filterPlan {
+	p := &filterPlan{
+		filters:   filters,
+		slots:     make(map[string]int),
+		groups:    make(map[string]struct{}),
+		rewriters: make([]func(string, slog.Attr) slog.Attr, len(filters)),
+	}
+	for i, filter := range filters {
+		p.addKeys(filter)
+		p.rewriters[i] = filter.rewriter()
+	}
+	return p
+}
+
+// rewriter returns the attr rewrite of f's action, which gets the qualified
+// path of the group the attr sits in, or nil when there is nothing to rewrite.
+func (f Filter) rewriter() func(prefix string, attr slog.Attr) slog.Attr {
+	var keys map[string]struct{}
+	if len(f.keys) > 0 {
+		keys = make(map[string]struct{}, len(f.keys))
+		for _, k := range f.keys {
+			keys[k] = struct{}{}
+		}
+	}
+
+	switch {
+	case f.action == shorten && keys != nil:
+		return editKeys(keys, func(attr slog.Attr) slog.Attr {
+			return slog.Attr{Key: attr.Key, Value: f.shortenValue(attr.Value)}
+		})
+	case f.action == hash && keys != nil && len(f.hashKey) == 0:
+		// refuse to hash without a secret; see Hash.
+		return editKeys(keys, func(attr slog.Attr) slog.Attr {
+			return slog.String(attr.Key, defaultMask)
+		})
+	case f.action == hash && keys != nil:
+		return editKeys(keys, func(attr slog.Attr) slog.Attr {
+			return slog.String(attr.Key, f.pseudonym(attr.Value.Resolve().String()))
+		})
+	case f.action == rename && keys != nil:
+		return editKeys(keys, func(attr slog.Attr) slog.Attr {
+			return slog.Attr{Key: f.to, Value: attr.Value}
+		})
+	case f.action == drop && keys != nil:
+		return editKeys(keys, func(slog.Attr) slog.Attr { return slog.Attr{} })
+	case f.action == redact && (keys != nil || len(f.redactors) > 0):
+		return func(prefix string, attr slog.Attr) slog.Attr {
+			return f.redactAttr(keys, prefix, attr)
+		}
+	}
+	return nil
+}
+
+// addKeys assigns slots to the attribute keys filter reads.
+func (p *filterPlan) addKeys(filter Filter) {
+	for key := range filter.attributes {
+		p.addKey(key)
+	}
+	for key := range filter.attrRegexps {
+		p.addKey(key)
+	}
+	for _, pred := range filter.predicates {
+		p.addKey(pred.key)
+	}
+	for _, nested := range [][]Filter{filter.all, filter.anyOf, filter.none} {
+		for _, criteria := range nested {
+			p.addKeys(criteria)
+		}
+	}
+}
+
+// addKey assigns key a slot and records the group paths leading to it.
+func (p *filterPlan) addKey(key string) {
+	if _, ok := p.slots[key]; ok {
+		return
+	}
+	p.slots[key] = len(p.slots)
+	p.groups[""] = struct{}{}
+	for i := 0; i < len(key); i++ {
+		if key[i] == '.' {
+			p.groups[key[:i]] = struct{}{}
+		}
+	}
+}
+
+// collect fills values, which has a slot for each key of p, from the record
+// message, the attrs held by f, and the record's own attrs, in a single pass.
+// Later attrs win over earlier ones with the same key. Once a filter has
+// rewritten attrs, scope and attrs are Handle's working copies of the held and
+// record attrs and are read instead; both are nil before that.
+func (p *filterPlan) collect(f *FilterHandler, record slog.Record, scope []scopedAttrs, attrs []slog.Attr, values []attrSlot) {
+	if len(p.slots) == 0 {
+		return
+	}
+	clear(values)
+	if slot, ok := p.slots["msg"]; ok {
+		values[slot] = attrSlot{value: slog.StringValue(record.Message), present: true}
+	}
+
+	rewritten := scope != nil
+	if !rewritten {
+		scope = f.attrs
+	}
+	var buf [128]byte
+	for _, s := range scope {
+		prefix := appendGroups(buf[:0], f.groups[:s.depth])
+		if _, ok := p.groups[string(prefix)]; !ok {
+			continue
+		}
+		for _, attr := range s.attrs {
+			p.collectAttr(values, prefix, attr)
+		}
+	}
+	prefix := appendGroups(buf[:0], f.groups)
+	if _, ok := p.groups[string(prefix)]; !ok {
+		return
+	}
+	if rewritten {
+		for _, attr := range attrs {
+			p.collectAttr(values, prefix, attr)
+		}
+		return
+	}
+	record.Attrs(func(attr slog.Attr) bool {
+		p.collectAttr(values, prefix, attr)
+		return true
+	})
+}
+
+// collectAttr stores attr's value in its slot, if it has one, descending into
+// groups that lead to a slot. prefix is the qualified path of the enclosing
+// group; its spare capacity is reused to build keys without allocating.
+func (p *filterPlan) collectAttr(values []attrSlot, prefix []byte, attr slog.Attr) {
+	key := appendKey(prefix, attr.Key)
+	slot, isKey := p.slots[string(key)]
+	_, isGroup := p.groups[string(key)]
+	if !isKey && !isGroup {
+		// skip without resolving: nothing reads this attr.
+		return
+	}
+	attr.Value = attr.Value.Resolve()
+	if attr.Value.Kind() == slog.KindGroup {
+		if isGroup {
+			for _, member := range attr.Value.Group() {
+				p.collectAttr(values, key, member)
+			}
+		}
+		return
+	}
+	if isKey {
+		values[slot] = attrSlot{value: attr.Value, present: true}
+	}
+}
+
+// appendGroups appends the dotted group path to buf.
+func appendGroups(buf []byte, groups []string) []byte {
+	for _, g := range groups {
+		buf = appendKey(buf, g)
+	}
+	return buf
+}
+
+// appendKey appends key to the qualified path prefix, as qualify does.
+func appendKey(prefix []byte, key string) []byte {
+	if key == "" {
+		return prefix
+	}
+	if len(prefix) > 0 {
+		prefix = append(prefix, '.')
+	}
+	return append(prefix, key...)
+}
+
+// matches reports whether record satisfies every set criterion of filter,
+// given the values collected for p.
+func (p *filterPlan) matches(record slog.Record, filter Filter, values []attrSlot) bool {
+	if filter.levels != nil && !filter.levels.contains(record.Level) {
+		return false
+	}
+
+	if filter.message != nil && !filter.message.match(record.Message) {
+		return false
+	}
+	if filter.messageRegexp != nil && !filter.messageRegexp.MatchString(record.Message) {
+		return false
+	}
+
+	for key, pattern := range filter.attributes {
+		v := values[p.slots[key]]
+		if !v.present {
+			// an absent attribute reads as "", which only an exact "" matches.
+			if pattern.wildcard() || pattern.raw != "" {
+				return false
+			}
+			continue
+		}
+		if !pattern.match(v.value.String()) {
+			return false
+		}
+	}
+	for key, re := range filter.attrRegexps {
+		v := values[p.slots[key]]
+		if !v.present || !re.MatchString(v.value.String()) {
+			return false
+		}
+	}
+	for _, pred := range filter.predicates {
+		v := values[p.slots[pred.key]]
+		if !pred.match(v.value, v.present) {
+			return false
+		}
+	}
+
+	for _, criteria := range filter.all {
+		if !p.matches(record, criteria, values) {
+			return false
+		}
+	}
+	if len(filter.anyOf) > 0 {
+		matched := false
+		for _, criteria := range filter.anyOf {
+			if p.matches(record, criteria, values) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return false
+		}
+	}
+	for _, criteria := range filter.none {
+		if p.matches(record, criteria, values) {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,323 @@
+package log
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// RateLimitOptions configures a RateLimitHandler.
+type RateLimitOptions struct {
+	// Rate is how many records per second each kind of record may log once
+	// its burst is spent (default 1).
+	Rate float64
+	// Burst is how many records of a kind may log at once (default 1).
+	Burst int
+	// Attrs name attributes, group-qualified as for filters, whose values
+	// join the level and message in telling kinds of records apart, so
+	// {"route"} limits each route's failures on their own.
+	Attrs []string
+	// Interval is how often the count of suppressed records of each kind is
+	// reported (default 10s).
+	Interval time.Duration
+}
+
+// RateLimitHandler is a slog.Handler that limits how often records of each
+// kind, by level, message and the attributes in RateLimitOptions.Attrs, reach
+// the wrapped handler, with a token bucket per kind. Records over the limit are
+// counted instead, and each Interval a summary at the same level reports them:
+//
+//	level=ERROR msg="suppressed 4312 similar records" message="db unreachable" suppressed=4312 route=/pay
+//
+// Summaries are written by a background goroutine, so a kind that went quiet
+// is still reported. Call Close before exiting to stop it and write the
+// summaries still pending. Buckets are shared by every handler derived with
+// WithAttrs or WithGroup.
+type RateLimitHandler struct {
+	handler slog.Handler
+	state   *rateLimitState
+	// prefix is the dotted path of the open groups.
+	prefix string
+	// held are the values of key attributes added with WithAttrs.
+	held map[string]slog.Value
+}
+
+// rateLimitState is the configuration and buckets shared by derived
+// RateLimitHandlers.
+type rateLimitState struct {
+	// handler is the wrapped handler summaries go to, without any held attrs
+	// or groups.
+	handler  slog.Handler
+	rate     float64
+	burst    float64
+	attrs    []string
+	interval time.Duration
+	now      func() time.Time
+
+	mu        sync.Mutex
+	buckets   map[string]*rateBucket
+	lastSweep time.Time
+
+	// stop ends the goroutine writing summaries each interval, which closes
+	// done when it has exited.
+	stop     chan struct{}
+	stopOnce sync.Once
+	done     chan struct{}
+}
+
+// rateBucket is the token bucket of one kind of record.
+type rateBucket struct {
+	tokens     float64
+	last       time.Time
+	suppressed int
+	// level, message and attrs describe the kind in summaries.
+	level   slog.Level
+	message string
+	attrs   []slog.Attr
+}
+
+var _ slog.Handler = (*RateLimitHandler)(nil)
+
+// NewRateLimitHandler wraps handler, limiting records as opts describes, and
+// starts the goroutine that writes summaries.
+func NewRateLimitHandler(handler slog.Handler, opts RateLimitOptions) *RateLimitHandler {
+	state := &rateLimitState{
+		handler:  handler,
+		rate:     opts.Rate,
+		burst:    float64(max(opts.Burst, 1)),
+		attrs:    append([]string(nil), opts.Attrs...),
+		interval: opts.Interval,
+		now:      time.Now,
+		buckets:  make(map[string]*rateBucket),
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+	if state.rate <= 0 {
+		// a bucket that never refills would silence its kind for good.
+		state.rate = 1
+	}
+	if state.interval <= 0 {
+		state.interval = 10 * time.Second
+	}
+	state.lastSweep = state.now()
+	go state.run()
+	return &RateLimitHandler{handler: handler, state: state}
+}
+
+// Enabled reports whether the wrapped handler is enabled at level.
+func (h *RateLimitHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.handler.Enabled(ctx, level)
+}
+
+// Handle forwards the record if its kind is within the limit and counts it
+// otherwise, first writing any summaries that are due.
+func (h *RateLimitHandler) Handle(ctx context.Context, record slog.Record) error {
+	attrs := h.keyAttrs(record)
+	key := rateKey(record, attrs)
+
+	s := h.state
+	s.mu.Lock()
+	now := s.now()
+	var summaries []slog.Record
+	if now.Sub(s.lastSweep) >= s.interval {
+		summaries = s.sweep(now)
+	}
+	b, ok := s.buckets[key]
+	if !ok {
+		b = &rateBucket{tokens: s.burst, last: now, level: record.Level, message: record.Message, attrs: attrs}
+		s.buckets[key] = b
+	}
+	allowed := b.take(now, s.rate, s.burst)
+	if !allowed {
+		b.suppressed++
+	}
+	s.mu.Unlock()
+
+	errs := s.emit(ctx, summaries)
+	if allowed {
+		errs = append(errs, h.handler.Handle(ctx, record))
+	}
+	return errors.Join(errs...)
+}
+
+// Flush writes a summary for every kind with suppressed records now, rather
+// than at the next Interval; call it before shutting down.
+func (h *RateLimitHandler) Flush(ctx context.Context) error {
+	s := h.state
+	s.mu.Lock()
+	summaries := s.sweep(s.now())
+	s.mu.Unlock()
+	return errors.Join(s.emit(ctx, summaries)...)
+}
+
+// Close stops the goroutine writing summaries and writes those still pending,
+// as Flush does, or gives up when ctx ends. Records logged after Close are
+// still limited, their summaries written as records come in or by Flush.
+// Close is shared by every derived handler and may be called more than once.
+func (h *RateLimitHandler) Close(ctx context.Context) error {
+	s := h.state
+	s.stopOnce.Do(func() { close(s.stop) })
+	select {
+	case <-s.done:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	return h.Flush(ctx)
+}
+
+// run writes the summaries due each interval until stop is closed.
+func (s *rateLimitState) run() {
+	defer close(s.done)
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			s.mu.Lock()
+			summaries := s.sweep(s.now())
+			s.mu.Unlock()
+			// no caller to return an error to.
+			_ = s.emit(context.Background(), summaries)
+		case <-s.stop:
+			return
+		}
+	}
+}
+
+// take refills b for the time since it was last used and takes a token if
+// there is one.
+func (b *rateBucket) take(now time.Time, rate, burst float64) bool {
+	b.refill(now, rate, burst)
+	if b.tokens < 1 {
+		return false
+	}
+	b.tokens--
+	return true
+}
+
+// refill adds the tokens earned since b was last used, up to burst.
+func (b *rateBucket) refill(now time.Time, rate, burst float64) {
+	if elapsed := now.Sub(b.last); elapsed > 0 {
+		b.tokens = min(burst, b.tokens+elapsed.Seconds()*rate)
+		b.last = now
+	}
+}
+
+// sweep returns a summary for each kind with suppressed records, resetting
+// the counts, and forgets kinds whose bucket has refilled, so buckets for
+// one-off messages do not pile up. The caller holds s.mu.
+func (s *rateLimitState) sweep(now time.Time) []slog.Record {
+	s.lastSweep = now
+	var summaries []slog.Record
+	for key, b := range s.buckets {
+		if b.suppressed > 0 {
+			summaries = append(summaries, b.summary(now))
+			b.suppressed = 0
+			continue
+		}
+		b.refill(now, s.rate, s.burst)
+		if b.tokens >= s.burst {
+			delete(s.buckets, key)
+		}
+	}
+	return summaries
+}
+
+// summary builds the record reporting b's suppressed records.
+func (b *rateBucket) summary(now time.Time) slog.Record {
+	r := slog.NewRecord(now, b.level, "suppressed "+strconv.Itoa(b.suppressed)+" similar records", 0)
+	r.AddAttrs(slog.String("message", b.message), slog.Int("suppressed", b.suppressed))
+	r.AddAttrs(b.attrs...)
+	return r
+}
+
+// emit writes summaries to the wrapped handler, returning any errors.
+func (s *rateLimitState) emit(ctx context.Context, summaries []slog.Record) []error {
+	var errs []error
+	for _, r := range summaries {
+		if err := s.handler.Handle(ctx, r); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errs
+}
+
+// keyAttrs returns the key attributes of record, as found on it or held by h,
+// under their qualified names. Missing ones are left out.
+func (h *RateLimitHandler) keyAttrs(record slog.Record) []slog.Attr {
+	if len(h.state.attrs) == 0 {
+		return nil
+	}
+	attrs := make([]slog.Attr, 0, len(h.state.attrs))
+	for _, key := range h.state.attrs {
+		value, ok := h.held[key]
+		record.Attrs(func(attr slog.Attr) bool {
+			if v, found := findAttr(h.prefix, attr, key); found {
+				value, ok = v, true
+				return false
+			}
+			return true
+		})
+		if ok {
+			attrs = append(attrs, slog.Attr{Key: key, Value: value})
+		}
+	}
+	return attrs
+}
+
+// rateKey identifies the kind of record: its level, message and key attrs.
+func rateKey(record slog.Record, attrs []slog.Attr) string {
+	var b strings.Builder
+	b.WriteString(record.Level.String())
+	b.WriteByte(0)
+	b.WriteString(record.Message)
+	for _, attr := range attrs {
+		b.WriteByte(0)
+		b.WriteString(attr.Key)
+		b.WriteByte('=')
+		b.WriteString(attr.Value.String())
+	}
+	return b.String()
+}
+
+// WithAttrs returns a RateLimitHandler sharing the same buckets around the
+// wrapped handler's WithAttrs, remembering any key attributes among attrs.
+func (h *RateLimitHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	child := *h
+	child.handler = h.handler.WithAttrs(attrs)
+	copied := false
+	for _, key := range h.state.attrs {
+		for _, attr := range attrs {
+			v, ok := findAttr(h.prefix, attr, key)
+			if !ok {
+				continue
+			}
+			if !copied {
+				// copy on first write, leaving h's map alone.
+				child.held = make(map[string]slog.Value, len(h.held)+1)
+				for k, hv := range h.held {
+					child.held[k] = hv
+				}
+				copied = true
+			}
+			child.held[key] = v
+		}
+	}
+	return &child
+}
+
+// WithGroup returns a RateLimitHandler sharing the same buckets around the
+// wrapped handler's WithGroup.
+func (h *RateLimitHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	child := *h
+	child.handler = h.handler.WithGroup(name)
+	child.prefix = qualify(h.prefix, name)
+	return &child
+}
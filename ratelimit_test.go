@@ -0,0 +1,179 @@
+package log
+
+import (
+	"context"
+	"log/slog"
+	"testing"
+	"time"
+)
+
+// fakeClock is a settable time source for handlers that read the time.
+type fakeClock struct{ t time.Time }
+
+func (c *fakeClock) now() time.Time { return c.t }
+
+func (c *fakeClock) advance(d time.Duration) { c.t = c.t.Add(d) }
+
+func newTestRateLimiter(down slog.Handler, opts RateLimitOptions) (*RateLimitHandler, *fakeClock) {
+	clock := &fakeClock{t: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}
+	h := NewRateLimitHandler(down, opts)
+	// stop the background sweeps: the tests drive them with the clock.
+	_ = h.Close(context.Background())
+	h.state.now = clock.now
+	h.state.lastSweep = clock.t
+	return h, clock
+}
+
+func Test_RateLimitHandler_Handle(t *testing.T) {
+	down := newRecHandler(LevelTrace)
+	h, clock := newTestRateLimiter(down, RateLimitOptions{Rate: 1, Burst: 2, Interval: time.Minute})
+	logger := slog.New(h)
+
+	for i := 0; i < 100; i++ {
+		logger.Error("db unreachable")
+	}
+	logger.Warn("db unreachable") // another level is another kind
+	clock.advance(time.Second)
+	logger.Error("db unreachable") // one token earned back
+
+	var messages []string
+	for _, rec := range down.seen() {
+		messages = append(messages, rec.Level.String()+" "+rec.Message)
+	}
+	want := []string{"ERROR db unreachable", "ERROR db unreachable", "WARN db unreachable", "ERROR db unreachable"}
+	if len(messages) != len(want) {
+		t.Fatalf("forwarded %q, want %q", messages, want)
+	}
+	for i := range want {
+		if messages[i] != want[i] {
+			t.Fatalf("forwarded %q, want %q", messages, want)
+		}
+	}
+
+	clock.advance(time.Minute)
+	logger.Info("unrelated")
+	seen := down.seen()
+	summary := seen[len(seen)-2]
+	if summary.Message != "suppressed 98 similar records" || summary.Level != slog.LevelError {
+		t.Fatalf("summary = %v %q, want ERROR \"suppressed 98 similar records\"", summary.Level, summary.Message)
+	}
+	if got := attrsOf(summary); got["message"] != "db unreachable" || got["suppressed"] != "98" {
+		t.Fatalf("summary attrs = %v", got)
+	}
+	if seen[len(seen)-1].Message != "unrelated" {
+		t.Fatalf("last record = %q, want the one that triggered the summary", seen[len(seen)-1].Message)
+	}
+}
+
+func Test_RateLimitHandler_Attrs(t *testing.T) {
+	down := newRecHandler(LevelTrace)
+	h, _ := newTestRateLimiter(down, RateLimitOptions{Rate: 0, Burst: 1, Attrs: []string{"http.route"}})
+	logger := slog.New(h)
+
+	pay := logger.WithGroup("http").With("route", "/pay")
+	for i := 0; i < 3; i++ {
+		pay.Error("failed")
+		logger.Error("failed", slog.Group("http", "route", "/refund"))
+	}
+	if n := len(down.seen()); n != 2 {
+		t.Fatalf("forwarded %d records, want one per route", n)
+	}
+
+	if err := h.Flush(context.Background()); err != nil {
+		t.Fatalf("Flush() error = %v", err)
+	}
+	summaries := down.seen()[2:]
+	if len(summaries) != 2 {
+		t.Fatalf("Flush wrote %d summaries, want 2", len(summaries))
+	}
+	routes := map[string]bool{}
+	for _, rec := range summaries {
+		got := attrsOf(rec)
+		if got["suppressed"] != "2" {
+			t.Fatalf("summary attrs = %v, want suppressed=2", got)
+		}
+		routes[got["http.route"]] = true
+	}
+	if !routes["/pay"] || !routes["/refund"] {
+		t.Fatalf("summaries cover routes %v, want /pay and /refund", routes)
+	}
+
+	if err := h.Flush(context.Background()); err != nil || len(down.seen()) != 4 {
+		t.Fatalf("second Flush wrote %d more records, want none", len(down.seen())-4)
+	}
+}
+
+func Test_RateLimitHandler_SweepForgetsIdleKinds(t *testing.T) {
+	h, clock := newTestRateLimiter(noopHandler{}, RateLimitOptions{Rate: 1, Interval: time.Second})
+	logger := slog.New(h)
+	for i := 0; i < 50; i++ {
+		logger.Info("message " + time.Duration(i).String())
+	}
+	clock.advance(2 * time.Second)
+	logger.Info("later")
+
+	if n := len(h.state.buckets); n != 1 {
+		t.Fatalf("%d buckets left after the sweep, want only the new one", n)
+	}
+}
+
+func Test_New_WithOutput_RateLimit(t *testing.T) {
+	down := newRecHandler(LevelTrace)
+	limited := NewRateLimitHandler(down, RateLimitOptions{})
+	t.Cleanup(func() { _ = limited.Close(context.Background()) })
+	logger := New(WithOutput(limited))
+	for i := 0; i < 5; i++ {
+		logger.With("i", i).Error("boom")
+	}
+	if n := len(down.seen()); n != 1 {
+		t.Fatalf("forwarded %d records, want 1", n)
+	}
+}
+
+func Test_RateLimitHandler_SummarizesWhenQuiet(t *testing.T) {
+	down := newRecHandler(LevelTrace)
+	h := NewRateLimitHandler(down, RateLimitOptions{Rate: 0.001, Interval: 20 * time.Millisecond})
+	t.Cleanup(func() { _ = h.Close(context.Background()) })
+	logger := slog.New(h)
+
+	for i := 0; i < 5; i++ {
+		logger.Error("db unreachable")
+	}
+	// no further records: the summary must come from the background sweep.
+	eventually(t, func() bool { return len(down.seen()) == 2 })
+	if got := attrsOf(down.seen()[1]); got["suppressed"] != "4" {
+		t.Fatalf("summary attrs = %v, want suppressed=4", got)
+	}
+}
+
+func Test_RateLimitHandler_Close(t *testing.T) {
+	down := newRecHandler(LevelTrace)
+	h := NewRateLimitHandler(down, RateLimitOptions{Interval: time.Hour})
+	logger := slog.New(h)
+	logger.Error("boom")
+	logger.Error("boom")
+
+	if err := h.Close(context.Background()); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+	if err := h.Close(context.Background()); err != nil {
+		t.Fatalf("second Close() error = %v", err)
+	}
+	if seen := down.seen(); len(seen) != 2 || attrsOf(seen[1])["suppressed"] != "1" {
+		t.Fatalf("forwarded %v, want boom and its summary", messagesOf(seen))
+	}
+}
+
+func Test_RateLimitHandler_DefaultRate(t *testing.T) {
+	down := newRecHandler(LevelTrace)
+	h, clock := newTestRateLimiter(down, RateLimitOptions{})
+	logger := slog.New(h)
+
+	logger.Error("boom")
+	logger.Error("boom")
+	clock.advance(time.Second)
+	logger.Error("boom")
+	if n := len(down.seen()); n != 2 {
+		t.Fatalf("forwarded %d records, want 2 (a zero Rate refills one a second)", n)
+	}
+}
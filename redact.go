@@ -0,0 +1,179 @@
+package log
+
+import (
+	"log/slog"
+	"regexp"
+)
+
+// defaultMask replaces redacted values unless a filter sets its own with
+// Filter.Mask.
+const defaultMask = "[REDACTED]"
+
+// Redact starts a filter that replaces the values of the given attribute keys
+// on matching records with "[REDACTED]". Keys are group-qualified as for
+// Shorten, and a key naming a group masks the whole group. Add Emails,
+// BearerTokens, CardNumbers or ValuesMatching to also mask secrets wherever
+// they appear in the message or an attribute value:
+//
+//	log.Redact("password", "http.headers.authorization")
+//	log.Redact().Emails().BearerTokens().CardNumbers().Mask("***")
+//
+// Put Redact filters first: filters run in order, and a Redact only rewrites
+// the records it matches.
+func Redact(keys ...string) Filter {
+	return Filter{action: redact, keys: keys, mask: defaultMask}
+}
+
+// Mask sets the replacement for redacted values.
+func (f Filter) Mask(mask string) Filter {
+	f.mask = mask
+	return f
+}
+
+// Emails makes a Redact filter mask email addresses in the message and in
+// attribute values.
+func (f Filter) Emails() Filter {
+	return f.withRedactor(emailRedactor)
+}
+
+// BearerTokens makes a Redact filter mask bearer tokens, as in
+// "Authorization: Bearer <token>", keeping the "Bearer " prefix.
+func (f Filter) BearerTokens() Filter {
+	return f.withRedactor(bearerRedactor)
+}
+
+// CardNumbers makes a Redact filter mask payment card numbers: runs of 13 to
+// 19 digits, optionally separated by spaces or dashes, that pass the Luhn
+// check. Integer values are left alone, as about one in ten timestamps or IDs
+// of that length passes the check too; log card numbers as strings.
+func (f Filter) CardNumbers() Filter {
+	return f.withRedactor(cardRedactor)
+}
+
+// ValuesMatching makes a Redact filter mask every match of re in the message
+// and in attribute values.
+func (f Filter) ValuesMatching(re *regexp.Regexp) Filter {
+	return f.withRedactor(redactor{re: re})
+}
+
+// withRedactor returns f with one more value redactor.
+func (f Filter) withRedactor(r redactor) Filter {
+	f.redactors = append(append([]redactor(nil), f.redactors...), r)
+	return f
+}
+
+// redactor masks the matches of a pattern within a string.
+type redactor struct {
+	// name identifies a built-in redactor in configs; custom ones have none.
+	name string
+	re   *regexp.Regexp
+	// prefix keeps the first submatch of each match, masking the rest.
+	prefix bool
+	// luhn only masks matches whose digits pass the Luhn check.
+	luhn bool
+}
+
+var (
+	emailRedactor = redactor{
+		name: "emails",
+		re:   regexp.MustCompile(`[A-Za-z0-9._%+-]+@[A-Za-z0-9-]+(?:\.[A-Za-z0-9-]+)*\.[A-Za-z]{2,}`),
+	}
+	bearerRedactor = redactor{
+		name:   "bearer_tokens",
+		re:     regexp.MustCompile(`(?i)(\bbearer\s+)[A-Za-z0-9\-._~+/]+=*`),
+		prefix: true,
+	}
+	cardRedactor = redactor{
+		name: "card_numbers",
+		re:   regexp.MustCompile(`\b\d(?:[ -]?\d){12,18}\b`),
+		luhn: true,
+	}
+	builtinRedactors = []redactor{emailRedactor, bearerRedactor, cardRedactor}
+)
+
+// apply returns s with r's matches replaced by mask.
+func (r redactor) apply(s, mask string) string {
+	return r.re.ReplaceAllStringFunc(s, func(match string) string {
+		if r.luhn && !luhnValid(match) {
+			return match
+		}
+		if r.prefix {
+			if loc := r.re.FindStringSubmatchIndex(match); len(loc) >= 4 && loc[3] >= 0 {
+				return match[:loc[3]] + mask
+			}
+		}
+		return mask
+	})
+}
+
+// luhnValid reports whether the digits in s pass the Luhn checksum; other
+// characters are skipped.
+func luhnValid(s string) bool {
+	sum, n := 0, 0
+	for i := len(s) - 1; i >= 0; i-- {
+		c := s[i]
+		if c < '0' || c > '9' {
+			continue
+		}
+		d := int(c - '0')
+		if n%2 == 1 {
+			d *= 2
+			if d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+		n++
+	}
+	return n > 0 && sum%10 == 0
+}
+
+// redactString masks the matches of every redactor of f in s.
+func (f Filter) redactString(s string) string {
+	return f.redactText(s, false)
+}
+
+// redactText is redactString, skipping the Luhn-checked redactors when s is
+// the text of an integer (see CardNumbers).
+func (f Filter) redactText(s string, integer bool) string {
+	for _, r := range f.redactors {
+		if integer && r.luhn {
+			continue
+		}
+		s = r.apply(s, f.mask)
+	}
+	return s
+}
+
+// redactAttr returns attr with its value masked if its qualified key is in
+// keys, or with the value redactors of f applied otherwise, descending into
+// groups. A masked value is never resolved, so a LogValuer holding a secret is
+// not even called.
+func (f Filter) redactAttr(keys map[string]struct{}, prefix string, attr slog.Attr) slog.Attr {
+	key := qualify(prefix, attr.Key)
+	if _, ok := keys[key]; ok {
+		return slog.String(attr.Key, f.mask)
+	}
+	value := attr.Value.Resolve()
+	switch value.Kind() {
+	case slog.KindGroup:
+		members := value.Group()
+		redacted := make([]slog.Attr, len(members))
+		for i, member := range members {
+			redacted[i] = f.redactAttr(keys, key, member)
+		}
+		return slog.Attr{Key: attr.Key, Value: slog.GroupValue(redacted...)}
+	case slog.KindString, slog.KindAny, slog.KindInt64, slog.KindUint64:
+		if len(f.redactors) == 0 {
+			break
+		}
+		s := value.String()
+		integer := value.Kind() == slog.KindInt64 || value.Kind() == slog.KindUint64
+		if masked := f.redactText(s, integer); masked != s {
+			return slog.String(attr.Key, masked)
+		}
+	default:
+		// floats, times and the like carry no secret text.
+	}
+	return slog.Attr{Key: attr.Key, Value: value}
+}
@@ -0,0 +1,175 @@
+package log
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// Reloader re-reads a Config file and applies its filters, root level, and
+// component levels to a live Logger built by NewFromConfig (or by New with
+// WithFilters), all in one step: a record is handled under either the old
+// config or the new one. A config without a level keeps the current one.
+// Outputs are fixed when the logger is built; changing them requires a new
+// logger. A config that fails to load or validate is not applied: the previous
+// one stays in effect and the failure is logged at WARN through the logger
+// itself.
+type Reloader struct {
+	logger Logger
+	path   string
+
+	mu sync.Mutex
+	// modTime and size identify the file last applied by Watch, and failed
+	// the last one that failed, so its failure is logged once.
+	modTime time.Time
+	size    int64
+	failed  struct {
+		modTime time.Time
+		size    int64
+	}
+}
+
+// NewReloader returns a Reloader applying the config at path to l.
+func NewReloader(l Logger, path string) *Reloader {
+	r := &Reloader{logger: l, path: path}
+	if info, err := os.Stat(path); err == nil {
+		r.modTime, r.size = info.ModTime(), info.Size()
+	}
+	return r
+}
+
+// Reload reads and applies the config now. On failure it logs a WARN, leaves
+// the previous config in place, and returns the error.
+func (r *Reloader) Reload() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.reload()
+}
+
+// reload is Reload with r.mu held.
+func (r *Reloader) reload() error {
+	err := r.apply()
+	r.report(err)
+	return err
+}
+
+// report logs the outcome of a reload.
+func (r *Reloader) report(err error) {
+	if err != nil {
+		r.logger.Warn("log config reload failed, keeping the previous config", "path", r.path, "err", err)
+		return
+	}
+	r.logger.Info("log config reloaded", "path", r.path)
+}
+
+// apply loads the config and, once all of it is valid, swaps it in.
+func (r *Reloader) apply() error {
+	l, ok := r.logger.(*logger)
+	if !ok || l.filters == nil {
+		return errors.New("log: logger has no FilterHandler; build it with NewFromConfig or WithFilters")
+	}
+
+	f, err := os.Open(r.path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	cfg, err := ReadConfig(f)
+	if err != nil {
+		return err
+	}
+
+	var level *slog.Level
+	if cfg.Level != "" {
+		parsed, err := ParseLevel(cfg.Level)
+		if err != nil {
+			return &ConfigError{Field: "level", Err: err}
+		}
+		level = &parsed
+	}
+	filters, err := cfg.filters()
+	if err != nil {
+		return err
+	}
+	components := make(map[string]slog.Level, len(cfg.Components))
+	for name, value := range cfg.Components {
+		if components[name], err = ParseLevel(value); err != nil {
+			return &ConfigError{Field: fmt.Sprintf("components[%q]", name), Err: err}
+		}
+	}
+
+	plan := newFilterPlan(filters)
+	l.filters.settings.update(func(s *settings) {
+		s.plan, s.overrides = plan, components
+		if level != nil {
+			s.level = *level
+		}
+	})
+	return nil
+}
+
+// Watch polls the config file every interval and reloads it when its
+// modification time or size changes, until ctx is done.
+func (r *Reloader) Watch(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			r.reloadIfChanged()
+		}
+	}
+}
+
+// reloadIfChanged reloads when the file's modification time or size differs
+// from the last one applied. A missing file is left for the next poll, and so
+// is one that fails to load, which may be half written.
+func (r *Reloader) reloadIfChanged() {
+	info, err := os.Stat(r.path)
+	if err != nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if info.ModTime().Equal(r.modTime) && info.Size() == r.size {
+		return
+	}
+	err = r.apply()
+	switch {
+	case err == nil:
+		r.modTime, r.size = info.ModTime(), info.Size()
+	case info.ModTime().Equal(r.failed.modTime) && info.Size() == r.failed.size:
+		// retried quietly: this version's failure is already logged.
+		return
+	default:
+		r.failed.modTime, r.failed.size = info.ModTime(), info.Size()
+	}
+	r.report(err)
+}
+
+// WatchSignal reloads whenever the process receives one of sigs (SIGHUP when
+// none are given), until ctx is done.
+func (r *Reloader) WatchSignal(ctx context.Context, sigs ...os.Signal) {
+	if len(sigs) == 0 {
+		sigs = []os.Signal{syscall.SIGHUP}
+	}
+	ch := make(chan os.Signal, 1)
+	signal.Notify(ch, sigs...)
+	defer signal.Stop(ch)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ch:
+			_ = r.Reload()
+		}
+	}
+}
@@ -0,0 +1,215 @@
+package log
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// hookHandler is a recHandler that calls hook before recording each record.
+type hookHandler struct {
+	*recHandler
+	hook func()
+}
+
+func (h *hookHandler) Handle(ctx context.Context, r slog.Record) error {
+	h.hook()
+	return h.recHandler.Handle(ctx, r)
+}
+
+// writeConfig writes a JSON config to path, failing the test on error.
+func writeConfig(t *testing.T, path, cfg string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(cfg), 0o600); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+}
+
+func Test_Reloader_Reload_SwapsFiltersAndLevels(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "log.json")
+	writeConfig(t, path, `{"level": "warn"}`)
+
+	var buf lockedBuffer
+	logger := New(WithText(&buf), WithLevel(slog.LevelWarn), WithFilters())
+	db := logger.Named("db")
+	child := logger.With("k", "v")
+	r := NewReloader(logger, path)
+
+	writeConfig(t, path, `{
+		"level": "info",
+		"filters": [{"action": "deny", "message": "noise"}],
+		"components": {"db": "debug"}
+	}`)
+	if err := r.Reload(); err != nil {
+		t.Fatalf("Reload() error = %v", err)
+	}
+
+	logger.Info("root-info")
+	logger.Info("noise")
+	db.Debug("db-debug")
+	child.Debug("child-debug")
+
+	out := buf.String()
+	for _, want := range []string{"root-info", "db-debug", "log config reloaded"} {
+		if !strings.Contains(out, want) {
+			t.Fatalf("output missing %s after reload: %q", want, out)
+		}
+	}
+	for _, unwanted := range []string{"msg=noise", "child-debug"} {
+		if strings.Contains(out, unwanted) {
+			t.Fatalf("output has %s after reload: %q", unwanted, out)
+		}
+	}
+}
+
+func Test_Reloader_Reload_KeepsPreviousConfigOnError(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  string
+	}{
+		{"invalid json", `{"level": `},
+		{"invalid level", `{"level": "loud", "filters": [{"action": "deny", "message": "x"}]}`},
+		{"invalid filter", `{"level": "error", "filters": [{"action": "drop"}]}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "log.json")
+			var buf lockedBuffer
+			logger := New(WithText(&buf), WithLevel(slog.LevelInfo), WithFilters(Deny().Message("secret")))
+			r := NewReloader(logger, path)
+
+			writeConfig(t, path, tt.cfg)
+			if err := r.Reload(); err == nil {
+				t.Fatal("Reload() error = nil, want the config error")
+			}
+
+			logger.Info("secret")
+			logger.Info("still-info")
+			out := buf.String()
+			if !strings.Contains(out, "level=WARN") || !strings.Contains(out, "reload failed") {
+				t.Fatalf("failed reload was not logged at WARN: %q", out)
+			}
+			if strings.Contains(out, "msg=secret") || !strings.Contains(out, "still-info") {
+				t.Fatalf("previous filters or level were not kept: %q", out)
+			}
+		})
+	}
+}
+
+func Test_Reloader_Reload_RequiresFilterHandler(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "log.json")
+	writeConfig(t, path, `{}`)
+
+	var buf lockedBuffer
+	r := NewReloader(New(WithText(&buf)), path)
+	if err := r.Reload(); err == nil {
+		t.Fatal("Reload() error = nil, want an error for a logger without a FilterHandler")
+	}
+}
+
+func Test_Reloader_Watch_ReloadsOnChange(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "log.json")
+	writeConfig(t, path, `{"level": "info"}`)
+
+	var buf lockedBuffer
+	logger := New(WithText(&buf), WithLevel(slog.LevelInfo), WithFilters())
+	r := NewReloader(logger, path)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		r.Watch(ctx, 5*time.Millisecond)
+	}()
+	t.Cleanup(func() {
+		cancel()
+		<-done
+	})
+
+	writeConfig(t, path, `{"level": "error"}`)
+	eventually(t, func() bool { return logger.Level() == slog.LevelError })
+}
+
+func Test_Reloader_Reload_KeepsLevelWhenUnset(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "log.json")
+	writeConfig(t, path, `{"filters": [{"action": "deny", "message": "noise"}]}`)
+
+	var buf lockedBuffer
+	logger := New(WithText(&buf), WithLevel(slog.LevelWarn), WithFilters())
+	if err := NewReloader(logger, path).Reload(); err != nil {
+		t.Fatalf("Reload() error = %v", err)
+	}
+	if got := logger.Level(); got != slog.LevelWarn {
+		t.Fatalf("Level() = %v after a config without a level, want WARN kept", got)
+	}
+}
+
+func Test_Reloader_Watch_RetriesFailedVersion(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "log.json")
+	var buf lockedBuffer
+	logger := New(WithText(&buf), WithLevel(slog.LevelInfo), WithFilters())
+	r := NewReloader(logger, path)
+
+	// a half-written file, then the full one with the same size and mtime.
+	stamp := time.Now().Add(-time.Hour).Truncate(time.Second)
+	writeConfig(t, path, `{"level": "error"`)
+	if err := os.Chtimes(path, stamp, stamp); err != nil {
+		t.Fatalf("chtimes: %v", err)
+	}
+	r.reloadIfChanged()
+	r.reloadIfChanged()
+	if n := strings.Count(buf.String(), "reload failed"); n != 1 {
+		t.Fatalf("failure logged %d times, want once: %q", n, buf.String())
+	}
+
+	writeConfig(t, path, `{"level":"error"}`)
+	if err := os.Chtimes(path, stamp, stamp); err != nil {
+		t.Fatalf("chtimes: %v", err)
+	}
+	r.reloadIfChanged()
+	if got := logger.Level(); got != slog.LevelError {
+		t.Fatalf("Level() = %v, want the fixed file applied", got)
+	}
+}
+
+func Test_Reloader_Reload_FromAnOutput(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "log.json")
+	writeConfig(t, path, `{"level": "error", "filters": [{"action": "deny", "message": "x"}]}`)
+
+	// the output reloads while handling a record, and the reload logs
+	// through the same logger.
+	var (
+		r       *Reloader
+		started atomic.Bool
+	)
+	reloaded := make(chan error, 1)
+	down := &hookHandler{recHandler: newRecHandler(LevelTrace), hook: func() {
+		if started.CompareAndSwap(false, true) {
+			reloaded <- r.Reload()
+		}
+	}}
+	logger := New(WithOutput(down), WithLevel(slog.LevelInfo), WithFilters())
+	r = NewReloader(logger, path)
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		logger.Info("in flight")
+	}()
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("logging blocked on a reload started while handling a record")
+	}
+	if err := <-reloaded; err != nil {
+		t.Fatalf("Reload() error = %v", err)
+	}
+	if got := logger.Level(); got != slog.LevelError {
+		t.Fatalf("Level() = %v, want ERROR", got)
+	}
+}
@@ -0,0 +1,47 @@
+//go:build unix
+
+package log
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+	"testing"
+)
+
+func Test_Reloader_WatchSignal_ReloadsOnSIGHUP(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "log.json")
+	writeConfig(t, path, `{"level": "info"}`)
+
+	// catch SIGHUP in the test too, so a signal sent before the watcher has
+	// registered does not kill the test binary.
+	caught := make(chan os.Signal, 1)
+	signal.Notify(caught, syscall.SIGHUP)
+	t.Cleanup(func() { signal.Stop(caught) })
+
+	var buf lockedBuffer
+	logger := New(WithText(&buf), WithLevel(slog.LevelInfo), WithFilters())
+	r := NewReloader(logger, path)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		r.WatchSignal(ctx)
+	}()
+	t.Cleanup(func() {
+		cancel()
+		<-done
+	})
+
+	writeConfig(t, path, `{"level": "error"}`)
+	// the watcher may not have registered yet, so keep signalling until the
+	// reload lands.
+	eventually(t, func() bool {
+		_ = syscall.Kill(syscall.Getpid(), syscall.SIGHUP)
+		return logger.Level() == slog.LevelError
+	})
+}
@@ -0,0 +1,91 @@
+package log
+
+import "log/slog"
+
+// Rename starts a filter that renames the attribute from to the key to on
+// matching records. from is group-qualified as for Shorten; to is the new key
+// within the same group:
+//
+//	log.Rename("http.status_code", "status") // http.status_code -> http.status
+func Rename(from, to string) Filter {
+	return Filter{action: rename, keys: []string{from}, to: to}
+}
+
+// DropAttrs starts a filter that removes the given attribute keys from matching
+// records. Keys are group-qualified as for Shorten, and a key naming a group
+// drops the whole group.
+func DropAttrs(keys ...string) Filter {
+	return Filter{action: drop, keys: keys}
+}
+
+// AddAttrs starts a filter that adds attrs to matching records, as if they had
+// been passed to the logging call.
+func AddAttrs(attrs ...slog.Attr) Filter {
+	return Filter{action: add, added: append([]slog.Attr(nil), attrs...)}
+}
+
+// Relevel starts a filter that moves matching records to level, to downgrade a
+// noisy library's errors to warnings, say:
+//
+//	log.Relevel(slog.LevelWarn).Level(slog.LevelError).Attr("logger", "thirdparty*")
+//
+// Level criteria of later filters see the new level, as they see the attrs
+// other filters rewrote, so a Deny().Below after a Relevel drops the records it
+// moved below the cut-off, and the wrapped handler must be enabled at the new
+// level for the record to go out. A record below the logger's level never
+// reaches the filters, so Relevel cannot raise it.
+func Relevel(level slog.Level) Filter {
+	return Filter{action: relevel, relevel: level}
+}
+
+// editKeys returns a rewrite applying edit to each attr whose qualified key is
+// in keys, descending into the groups that lead to one. edit returns the zero
+// Attr to drop the attr.
+func editKeys(keys map[string]struct{}, edit func(slog.Attr) slog.Attr) func(string, slog.Attr) slog.Attr {
+	groups := make(map[string]struct{})
+	for key := range keys {
+		groups[""] = struct{}{}
+		for i := 0; i < len(key); i++ {
+			if key[i] == '.' {
+				groups[key[:i]] = struct{}{}
+			}
+		}
+	}
+
+	var rewrite func(prefix string, attr slog.Attr) slog.Attr
+	rewrite = func(prefix string, attr slog.Attr) slog.Attr {
+		key := qualify(prefix, attr.Key)
+		if _, ok := keys[key]; ok {
+			return edit(attr)
+		}
+		if _, ok := groups[key]; !ok {
+			return attr
+		}
+		value := attr.Value.Resolve()
+		if value.Kind() != slog.KindGroup {
+			return attr
+		}
+		// copy the members: the group may be shared with the caller's record.
+		members := append([]slog.Attr(nil), value.Group()...)
+		return slog.Attr{Key: attr.Key, Value: slog.GroupValue(rewriteAttrs(rewrite, key, members)...)}
+	}
+	return rewrite
+}
+
+// rewriteAttrs applies rewrite to each of attrs in place, leaving out the ones
+// it drops.
+func rewriteAttrs(rewrite func(string, slog.Attr) slog.Attr, prefix string, attrs []slog.Attr) []slog.Attr {
+	out := attrs[:0]
+	for _, attr := range attrs {
+		if attr = rewrite(prefix, attr); !emptyAttr(attr) {
+			out = append(out, attr)
+		}
+	}
+	return out
+}
+
+// emptyAttr reports whether attr is the zero Attr, which slog handlers ignore.
+// Attr.Equal is not used as it panics on uncomparable values.
+func emptyAttr(attr slog.Attr) bool {
+	return attr.Key == "" && attr.Value.Kind() == slog.KindAny && attr.Value.Any() == nil
+}
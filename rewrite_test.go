@@ -0,0 +1,91 @@
+package log
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func Test_FilterHandler_Handle_Rewrites(t *testing.T) {
+	var buf bytes.Buffer
+	h := NewFilterHandler(slog.NewJSONHandler(&buf, nil),
+		Rename("http.status_code", "status"),
+		Rename("svc", "service"),
+		DropAttrs("debug", "http.headers", "http.req.cookie").Message("request"),
+		AddAttrs(slog.String("source", "thirdparty")).Message("request"),
+	)
+	req := slog.Group("req", slog.String("cookie", "c=1"), slog.String("path", "/"))
+	logger := slog.New(h).With("svc", "api", "debug", true).WithGroup("http")
+	logger.Info("request", "status_code", 200, slog.Group("headers", "accept", "*/*"), req)
+	logger.Info("other", "status_code", 500, "debug", "kept")
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	want := `"msg":"request","service":"api","http":{"status":200,"req":{"path":"/"},"source":"thirdparty"}}`
+	if !strings.HasSuffix(lines[0], want) {
+		t.Fatalf("output = %s, want suffix %s", lines[0], want)
+	}
+	want = `"msg":"other","service":"api","debug":true,"http":{"status":500,"debug":"kept"}}`
+	if !strings.HasSuffix(lines[1], want) {
+		t.Fatalf("output = %s, want suffix %s", lines[1], want)
+	}
+	if members := req.Value.Group(); len(members) != 2 {
+		t.Fatalf("caller's group changed to %v", members)
+	}
+}
+
+func Test_FilterHandler_Handle_Relevel(t *testing.T) {
+	down := newRecHandler(slog.LevelInfo)
+	h := NewFilterHandler(down,
+		Relevel(slog.LevelWarn).Level(slog.LevelError).Attr("logger", "thirdparty"),
+		Relevel(slog.LevelDebug).Message("chatty"),
+		Deny().Level(slog.LevelWarn).Message("ignored"),
+	)
+	logger := slog.New(h)
+
+	logger.Error("boom", "logger", "thirdparty")
+	logger.Error("boom", "logger", "app")
+	logger.Info("chatty")
+	logger.Error("ignored", "logger", "thirdparty")
+
+	seen := down.seen()
+	if len(seen) != 2 {
+		t.Fatalf("got %d records, want 2 (chatty and ignored dropped)", len(seen))
+	}
+	if seen[0].Level != slog.LevelWarn || seen[1].Level != slog.LevelError {
+		t.Fatalf("levels = %v, %v, want WARN, ERROR", seen[0].Level, seen[1].Level)
+	}
+
+	// a Relevel ahead of a level-only Deny may move records out of its reach.
+	h.SetFilters([]Filter{Relevel(slog.LevelWarn).Message("x"), Deny().Level(slog.LevelError)})
+	if !h.Enabled(context.Background(), slog.LevelError) {
+		t.Fatal("Enabled(ERROR) = false with a Relevel ahead of the Deny")
+	}
+}
+
+func Test_FilterHandler_Handle_LaterFiltersSeeRewrites(t *testing.T) {
+	down := newRecHandler(slog.LevelInfo)
+	logger := slog.New(NewFilterHandler(down,
+		Rename("user", "uid"),
+		Deny().Attr("uid", "bob"),
+		AddAttrs(slog.String("source", "thirdparty")).Message("vendor*"),
+		DropAttrs("secret").Attr("source", "thirdparty"),
+		Deny().AttrExists("secret").Message("vendor*"),
+	))
+
+	logger.Info("login", "user", "bob")
+	logger.Info("login", "user", "alice")
+	logger.Info("vendor call", "secret", "s3")
+
+	seen := down.seen()
+	if len(seen) != 2 {
+		t.Fatalf("got %d records, want 2 (bob's login denied after the rename)", len(seen))
+	}
+	if got := attrsOf(seen[0]); got["uid"] != "alice" {
+		t.Fatalf("attrs = %v, want uid=alice", got)
+	}
+	if got := attrsOf(seen[1]); got["source"] != "thirdparty" || got["secret"] != "" {
+		t.Fatalf("attrs = %v, want the added source to drive the drop of secret", got)
+	}
+}
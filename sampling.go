@@ -0,0 +1,224 @@
+package log
+
+import (
+	"context"
+	"hash/fnv"
+	"log/slog"
+	"strings"
+	"sync/atomic"
+)
+
+// SampleRate is how many records of a level a SamplingHandler keeps, built with
+// OneIn or Fraction.
+type SampleRate struct {
+	n        uint64
+	fraction float64
+}
+
+// OneIn keeps one record in n: the first, the (n+1)th, and so on. n <= 1
+// keeps every record.
+func OneIn(n int) SampleRate {
+	return SampleRate{n: uint64(max(n, 1))}
+}
+
+// Fraction keeps the given fraction of records, spread evenly: 0.25 keeps
+// every fourth. 0 keeps none, 1 or more every record.
+func Fraction(f float64) SampleRate {
+	return SampleRate{fraction: min(max(f, 0), 1)}
+}
+
+// keep reports whether the nth record (from 0) is kept.
+func (r SampleRate) keep(n uint64) bool {
+	if r.n > 0 {
+		return n%r.n == 0
+	}
+	// keep the record where the running share of kept records ticks over.
+	return uint64(float64(n+1)*r.fraction) > uint64(float64(n)*r.fraction)
+}
+
+// share is the share of records r keeps, in [0, 1].
+func (r SampleRate) share() float64 {
+	if r.n > 0 {
+		return 1 / float64(r.n)
+	}
+	return r.fraction
+}
+
+// keepHash reports whether a record whose key hashes to h is kept. The hash is
+// read as a point in [0, 1) and kept below the rate's share, so a key kept at
+// some rate is kept at any higher one, OneIn or Fraction alike: a request
+// sampled for its debug lines keeps its info lines too.
+func (r SampleRate) keepHash(h uint64) bool {
+	if r.n == 1 {
+		return true
+	}
+	return float64(h>>11)/(1<<53) < r.share()
+}
+
+// SamplingOptions configures a SamplingHandler.
+type SamplingOptions struct {
+	// Levels maps a level to the share of its records kept. Levels without an
+	// entry are kept in full.
+	Levels map[slog.Level]SampleRate
+	// Key names an attribute, group-qualified as for filters, whose value
+	// decides instead of a counter, so every record of a sampled request is
+	// kept together:
+	//
+	//	log.SamplingOptions{Levels: ..., Key: "trace_id"}
+	//
+	// Records without the attribute are counted as usual.
+	Key string
+	// Bypass is the level from which records are never sampled (default
+	// slog.LevelError, so errors and LevelFatal always get through).
+	Bypass slog.Leveler
+}
+
+// SamplingHandler is a slog.Handler that forwards only a share of the records
+// at each level to the wrapped handler, per SamplingOptions. Counters are
+// shared by every handler derived from it with WithAttrs or WithGroup, so
+// loggers built per request do not each restart the count.
+//
+// Put it under a FilterHandler, as WithSampling does, to sample what the
+// filters keep; wrap a single output passed to WithOutput to sample that
+// output alone.
+type SamplingHandler struct {
+	handler slog.Handler
+	state   *samplingState
+	// prefix is the dotted path of the open groups.
+	prefix string
+	// keyed and key hold the sampling key's value when an attribute added
+	// with WithAttrs set it.
+	keyed bool
+	key   string
+}
+
+// samplingState is the configuration and the counters shared by derived
+// SamplingHandlers.
+type samplingState struct {
+	levels map[slog.Level]SampleRate
+	counts map[slog.Level]*atomic.Uint64
+	key    string
+	bypass slog.Leveler
+}
+
+var _ slog.Handler = (*SamplingHandler)(nil)
+
+// NewSamplingHandler wraps handler, sampling records as opts describes.
+func NewSamplingHandler(handler slog.Handler, opts SamplingOptions) *SamplingHandler {
+	state := &samplingState{
+		levels: make(map[slog.Level]SampleRate, len(opts.Levels)),
+		counts: make(map[slog.Level]*atomic.Uint64, len(opts.Levels)),
+		key:    opts.Key,
+		bypass: opts.Bypass,
+	}
+	if state.bypass == nil {
+		state.bypass = slog.LevelError
+	}
+	for level, rate := range opts.Levels {
+		state.levels[level] = rate
+		state.counts[level] = new(atomic.Uint64)
+	}
+	return &SamplingHandler{handler: handler, state: state}
+}
+
+// Enabled reports whether the wrapped handler is enabled at level.
+func (h *SamplingHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.handler.Enabled(ctx, level)
+}
+
+// Handle forwards the record if it is sampled, and drops it otherwise.
+func (h *SamplingHandler) Handle(ctx context.Context, record slog.Record) error {
+	if h.sampled(record) {
+		return h.handler.Handle(ctx, record)
+	}
+	return nil
+}
+
+// sampled reports whether record is kept.
+func (h *SamplingHandler) sampled(record slog.Record) bool {
+	s := h.state
+	if record.Level >= s.bypass.Level() {
+		return true
+	}
+	rate, ok := s.levels[record.Level]
+	if !ok {
+		return true
+	}
+	if s.key != "" {
+		key, found := h.key, h.keyed
+		record.Attrs(func(attr slog.Attr) bool {
+			if v, ok := findAttr(h.prefix, attr, s.key); ok {
+				key, found = v.String(), true
+				return false
+			}
+			return true
+		})
+		if found {
+			return rate.keepHash(hashKey(key))
+		}
+	}
+	return rate.keep(s.counts[record.Level].Add(1) - 1)
+}
+
+// hashKey hashes a sampling key. FNV alone leaves the high bits of similar
+// keys ("trace-1", "trace-2") alike, so the murmur3 finalizer mixes them.
+func hashKey(key string) uint64 {
+	hash := fnv.New64a()
+	_, _ = hash.Write([]byte(key))
+	h := hash.Sum64()
+	h ^= h >> 33
+	h *= 0xff51afd7ed558ccd
+	h ^= h >> 33
+	h *= 0xc4ceb9fe1a85ec53
+	h ^= h >> 33
+	return h
+}
+
+// findAttr returns the value of the attribute with the qualified key, if attr
+// is it or a group holding it. prefix is the path of the enclosing group.
+func findAttr(prefix string, attr slog.Attr, key string) (slog.Value, bool) {
+	qualified := qualify(prefix, attr.Key)
+	if qualified == key {
+		return attr.Value.Resolve(), true
+	}
+	if qualified != "" && !strings.HasPrefix(key, qualified+".") {
+		return slog.Value{}, false
+	}
+	value := attr.Value.Resolve()
+	if value.Kind() != slog.KindGroup {
+		return slog.Value{}, false
+	}
+	for _, member := range value.Group() {
+		if v, ok := findAttr(qualified, member, key); ok {
+			return v, true
+		}
+	}
+	return slog.Value{}, false
+}
+
+// WithAttrs returns a SamplingHandler sharing the same counters around the
+// wrapped handler's WithAttrs, remembering the sampling key if attrs set it.
+func (h *SamplingHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	child := *h
+	child.handler = h.handler.WithAttrs(attrs)
+	if h.state.key != "" {
+		for _, attr := range attrs {
+			if v, ok := findAttr(h.prefix, attr, h.state.key); ok {
+				child.key, child.keyed = v.String(), true
+			}
+		}
+	}
+	return &child
+}
+
+// WithGroup returns a SamplingHandler sharing the same counters around the
+// wrapped handler's WithGroup.
+func (h *SamplingHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	child := *h
+	child.handler = h.handler.WithGroup(name)
+	child.prefix = qualify(h.prefix, name)
+	return &child
+}
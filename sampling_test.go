@@ -0,0 +1,179 @@
+package log
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"slices"
+	"testing"
+)
+
+func Test_SampleRate_keep(t *testing.T) {
+	tests := []struct {
+		name string
+		rate SampleRate
+		want string
+	}{
+		{"one in three", OneIn(3), "x..x..x..x"},
+		{"one in one", OneIn(1), "xxxxxxxxxx"},
+		{"one in zero keeps all", OneIn(0), "xxxxxxxxxx"},
+		{"quarter", Fraction(0.25), "...x...x.."},
+		{"half", Fraction(0.5), ".x.x.x.x.x"},
+		{"none", Fraction(0), ".........."},
+		{"all", Fraction(2), "xxxxxxxxxx"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := make([]byte, len(tt.want))
+			for i := range got {
+				got[i] = '.'
+				if tt.rate.keep(uint64(i)) {
+					got[i] = 'x'
+				}
+			}
+			if string(got) != tt.want {
+				t.Fatalf("kept %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_SamplingHandler_Handle(t *testing.T) {
+	down := newRecHandler(LevelTrace)
+	logger := slog.New(NewSamplingHandler(down, SamplingOptions{
+		Levels: map[slog.Level]SampleRate{
+			slog.LevelDebug: OneIn(5),
+			slog.LevelError: Fraction(0), // bypassed by default
+		},
+	}))
+
+	for i := 0; i < 10; i++ {
+		// counters are shared with derived loggers.
+		logger.With("i", i).Debug("tick")
+		logger.Info("info")
+		logger.Error("error")
+		logger.Log(context.Background(), LevelFatal, "fatal")
+	}
+
+	counts := make(map[slog.Level]int)
+	for _, rec := range down.seen() {
+		counts[rec.Level]++
+	}
+	want := map[slog.Level]int{slog.LevelDebug: 2, slog.LevelInfo: 10, slog.LevelError: 10, LevelFatal: 10}
+	if fmt.Sprint(counts) != fmt.Sprint(want) {
+		t.Fatalf("kept %v, want %v", counts, want)
+	}
+}
+
+func Test_SamplingHandler_Handle_Keyed(t *testing.T) {
+	down := newRecHandler(LevelTrace)
+	logger := slog.New(NewSamplingHandler(down, SamplingOptions{
+		Levels: map[slog.Level]SampleRate{slog.LevelDebug: Fraction(0.1), slog.LevelInfo: Fraction(0.5)},
+		Key:    "req.trace_id",
+		Bypass: LevelFatal,
+	}))
+
+	const traces = 1000
+	for i := 0; i < traces; i++ {
+		id := fmt.Sprintf("trace-%d", i)
+		// the key comes from With for some records and the record for others.
+		reqLog := logger.WithGroup("req").With("trace_id", id)
+		reqLog.Debug("start")
+		reqLog.Debug("end")
+		logger.Info("done", slog.Group("req", "trace_id", id))
+	}
+
+	debug, info := 0, 0
+	for _, rec := range down.seen() {
+		switch rec.Level {
+		case slog.LevelDebug:
+			debug++
+		case slog.LevelInfo:
+			info++
+		default:
+			t.Fatalf("unexpected %v record", rec.Level)
+		}
+	}
+	if debug%2 != 0 {
+		t.Fatalf("kept %d debug records, want start and end of each sampled trace together", debug)
+	}
+	if debug < 2*traces/20 || debug > 2*traces/5 {
+		t.Fatalf("kept %d of %d debug records, want about 10%%", debug, 2*traces)
+	}
+	if info < traces/3 || info > 2*traces/3 {
+		t.Fatalf("kept %d of %d info records, want about half", info, traces)
+	}
+}
+
+func Test_SamplingHandler_KeyedDecisionIsStable(t *testing.T) {
+	opts := SamplingOptions{Levels: map[slog.Level]SampleRate{slog.LevelInfo: Fraction(0.3)}, Key: "trace_id"}
+	a, b := NewSamplingHandler(noopHandler{}, opts), NewSamplingHandler(noopHandler{}, opts)
+	for i := 0; i < 100; i++ {
+		rec := newRecord(slog.LevelInfo, "msg", "trace_id", fmt.Sprint(i))
+		if a.sampled(rec) != b.sampled(rec) || a.sampled(rec) != a.sampled(rec) {
+			t.Fatalf("trace %d sampled inconsistently", i)
+		}
+	}
+}
+
+func Test_New_WithSampling(t *testing.T) {
+	down := newRecHandler(LevelTrace)
+	logger := New(
+		WithOutput(down),
+		WithFilters(Deny().Message("noise")),
+		WithSampling(SamplingOptions{Levels: map[slog.Level]SampleRate{slog.LevelInfo: OneIn(2)}}),
+	)
+
+	for i := 0; i < 4; i++ {
+		logger.Info("noise")
+		logger.Info("kept", "i", i)
+	}
+
+	seen := down.seen()
+	if len(seen) != 2 || attrsOf(seen[0])["i"] != "0" || attrsOf(seen[1])["i"] != "2" {
+		t.Fatalf("kept %d records, want i=0 and i=2 (denied records are not counted)", len(seen))
+	}
+}
+
+func Test_SamplingHandler_Handle_KeyedAcrossLevels(t *testing.T) {
+	rec := newRecHandler(LevelTrace)
+	logger := slog.New(NewSamplingHandler(rec, SamplingOptions{
+		Levels: map[slog.Level]SampleRate{
+			LevelTrace:      OneIn(300),
+			slog.LevelDebug: OneIn(100),
+			slog.LevelInfo:  Fraction(0.1),
+			slog.LevelWarn:  OneIn(3),
+		},
+		Key: "trace_id",
+	}))
+
+	levels := []slog.Level{LevelTrace, slog.LevelDebug, slog.LevelInfo, slog.LevelWarn}
+	for i := 0; i < 5000; i++ {
+		id := fmt.Sprintf("trace-%d", i)
+		for _, level := range levels {
+			logger.Log(context.Background(), level, "step", "trace_id", id)
+		}
+	}
+
+	kept := make(map[string][]slog.Level)
+	for _, r := range rec.seen() {
+		id := attrsOf(r)["trace_id"]
+		kept[id] = append(kept[id], r.Level)
+	}
+	counts := make(map[slog.Level]int)
+	for id, got := range kept {
+		// a trace kept at some level is kept at every level above it.
+		lowest := got[0]
+		for i, level := range levels[slices.Index(levels, lowest):] {
+			if i >= len(got) || got[i] != level {
+				t.Fatalf("%s kept at %v, want every level from %v up", id, got, lowest)
+			}
+		}
+		for _, level := range got {
+			counts[level]++
+		}
+	}
+	if counts[slog.LevelDebug] == 0 || counts[slog.LevelInfo] < 2*counts[slog.LevelDebug] || counts[slog.LevelWarn] < 2*counts[slog.LevelInfo] {
+		t.Fatalf("kept per level = %v, want rising shares", counts)
+	}
+}
@@ -0,0 +1,75 @@
+package log
+
+import (
+	"log/slog"
+	"sync/atomic"
+)
+
+// settings is one version of what a Reloader replaces as a whole: the
+// compiled filters, the root level and the component overrides. A logger
+// built by New shares one settingsVar between its FilterHandler, its root
+// level and its Components. Settings are immutable once stored; every change
+// stores a new version, so a record handled against one never sees part of
+// the next.
+type settings struct {
+	plan      *filterPlan
+	level     slog.Level
+	overrides map[string]slog.Level
+}
+
+// settingsVar holds the current settings.
+type settingsVar struct {
+	atomic.Pointer[settings]
+}
+
+// newSettingsVar returns a settingsVar with no filters, level Debug and no
+// overrides.
+func newSettingsVar() *settingsVar {
+	v := new(settingsVar)
+	v.Store(&settings{plan: emptyPlan, level: slog.LevelDebug})
+	return v
+}
+
+// update stores a new version made by edit from a copy of the current one,
+// retrying when another update stored one first. edit must replace, not
+// modify, the overrides map.
+func (v *settingsVar) update(edit func(*settings)) {
+	for {
+		old := v.Load()
+		next := *old
+		edit(&next)
+		if v.CompareAndSwap(old, &next) {
+			return
+		}
+	}
+}
+
+// rootLevel is the Leveler of a logger built by New. It lives in the logger's
+// settings so a Reloader can change it together with the filters.
+type rootLevel struct {
+	settings *settingsVar
+}
+
+var _ slog.Leveler = rootLevel{}
+
+// Level returns the current root level.
+func (l rootLevel) Level() slog.Level {
+	return l.settings.Load().level
+}
+
+// Set changes the root level.
+func (l rootLevel) Set(level slog.Level) {
+	l.settings.update(func(s *settings) { s.level = level })
+}
+
+// levelIn returns the root level in s.
+func (l rootLevel) levelIn(s *settings) slog.Level {
+	return s.level
+}
+
+// settingsLeveler is implemented by Levelers that read settings, so
+// levelHandler can check a record against the same version its filters come
+// from.
+type settingsLeveler interface {
+	levelIn(s *settings) slog.Level
+}